@@ -41,6 +41,20 @@ func WinHTTPAddRequestHeaders(
 	return nil
 }
 
+// WinHTTPCloseHandle is WinHttpCloseHandle from winhttp.h
+func WinHTTPCloseHandle(hndl uintptr) error {
+	var e error
+	var proc string = "WinHttpCloseHandle"
+	var success uintptr
+
+	success, _, e = winhttp.NewProc(proc).Call(hndl)
+	if success == 0 {
+		return errors.Newf("%s: %w", proc, e)
+	}
+
+	return nil
+}
+
 // WinHTTPConnect is WinHttpConnect from winhttp.h
 func WinHTTPConnect(
 	sessionHndl uintptr,
@@ -250,6 +264,7 @@ func WinHTTPSendRequest(
 	headersLen int,
 	data []byte,
 	dataLen int,
+	totalLen int,
 ) error {
 	var body uintptr
 	var e error
@@ -267,7 +282,8 @@ func WinHTTPSendRequest(
 		uintptr(headersLen),
 		body,
 		uintptr(dataLen),
-		uintptr(dataLen),
+		uintptr(totalLen),
+		0,
 	)
 	if success == 0 {
 		return errors.Newf("%s: %w", proc, e)
@@ -299,3 +315,30 @@ func WinHTTPSetOption(hndl, opt uintptr, val []byte, valLen int) error {
 
 	return nil
 }
+
+// WinHTTPWriteData is WinHttpWriteData from winhttp.h
+func WinHTTPWriteData(
+	reqHndl uintptr,
+	data []byte,
+	bytesWritten *int64,
+) error {
+	var e error
+	var proc string = "WinHttpWriteData"
+	var success uintptr
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	success, _, e = winhttp.NewProc(proc).Call(
+		reqHndl,
+		uintptr(unsafe.Pointer(&data[0])),
+		uintptr(len(data)),
+		uintptr(unsafe.Pointer(bytesWritten)),
+	)
+	if success == 0 {
+		return errors.Newf("%s: %w", proc, e)
+	}
+
+	return nil
+}
@@ -9,6 +9,20 @@ import (
 	"github.com/mjwhitta/win/types"
 )
 
+// internetBuffersW is INTERNET_BUFFERSW from wininet.h
+type internetBuffersW struct {
+	StructSize    uint32
+	Next          uintptr
+	Header        uintptr
+	HeadersLength uint32
+	HeadersTotal  uint32
+	Buffer        uintptr
+	BufferLength  uint32
+	BufferTotal   uint32
+	OffsetLow     uint32
+	OffsetHigh    uint32
+}
+
 var wininet *syscall.LazyDLL = syscall.NewLazyDLL("Wininet")
 
 // HTTPAddRequestHeadersW is from wininet.h
@@ -41,6 +55,25 @@ func HTTPAddRequestHeadersW(
 	return nil
 }
 
+// HTTPEndRequestW is from wininet.h
+func HTTPEndRequestW(reqHndl uintptr, flags uintptr, context uintptr) error {
+	var e error
+	var proc string = "HttpEndRequestW"
+	var success uintptr
+
+	success, _, e = wininet.NewProc(proc).Call(
+		reqHndl,
+		0,
+		flags,
+		context,
+	)
+	if success == 0 {
+		return errors.Newf("%s: %w", proc, e)
+	}
+
+	return nil
+}
+
 // HTTPOpenRequestW is from wininet.h
 func HTTPOpenRequestW(
 	connHndl uintptr,
@@ -156,6 +189,56 @@ func HTTPSendRequestW(
 	return nil
 }
 
+// HTTPSendRequestExW is from wininet.h
+func HTTPSendRequestExW(
+	reqHndl uintptr,
+	headers string,
+	totalLen int,
+	flags uintptr,
+	context uintptr,
+) error {
+	var buffersIn internetBuffersW
+	var e error
+	var proc string = "HttpSendRequestExW"
+	var success uintptr
+
+	// Convert to Windows types
+	buffersIn = internetBuffersW{
+		BufferTotal:   uint32(totalLen),
+		Header:        types.LpCwstr(headers),
+		HeadersLength: uint32(len(headers)),
+		HeadersTotal:  uint32(len(headers)),
+		StructSize:    uint32(unsafe.Sizeof(buffersIn)),
+	}
+
+	success, _, e = wininet.NewProc(proc).Call(
+		reqHndl,
+		uintptr(unsafe.Pointer(&buffersIn)),
+		0,
+		flags,
+		context,
+	)
+	if success == 0 {
+		return errors.Newf("%s: %w", proc, e)
+	}
+
+	return nil
+}
+
+// InternetCloseHandle is from wininet.h
+func InternetCloseHandle(hndl uintptr) error {
+	var e error
+	var proc string = "InternetCloseHandle"
+	var success uintptr
+
+	success, _, e = wininet.NewProc(proc).Call(hndl)
+	if success == 0 {
+		return errors.Newf("%s: %w", proc, e)
+	}
+
+	return nil
+}
+
 // InternetConnectW is from wininet.h
 func InternetConnectW(
 	sessionHndl uintptr,
@@ -297,3 +380,30 @@ func InternetSetOptionW(
 
 	return nil
 }
+
+// InternetWriteFile is from wininet.h
+func InternetWriteFile(
+	reqHndl uintptr,
+	data []byte,
+	bytesWritten *int64,
+) error {
+	var e error
+	var proc string = "InternetWriteFile"
+	var success uintptr
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	success, _, e = wininet.NewProc(proc).Call(
+		reqHndl,
+		uintptr(unsafe.Pointer(&data[0])),
+		uintptr(len(data)),
+		uintptr(unsafe.Pointer(bytesWritten)),
+	)
+	if success == 0 {
+		return errors.Newf("%s: %w", proc, e)
+	}
+
+	return nil
+}
@@ -0,0 +1,87 @@
+package brotli
+
+import (
+	goerrors "errors"
+	"io"
+
+	"github.com/mjwhitta/win/errors"
+)
+
+// bitReader reads bits LSB first, as required by RFC 7932.
+type bitReader struct {
+	bits  uint64
+	nbits uint
+	r     io.ByteReader
+}
+
+// decodeError is used to unwind the decoder on failure. It is
+// recovered by Reader.Read.
+type decodeError struct {
+	error
+}
+
+// fail will abort decoding w/ the provided error message.
+func fail(format string, args ...any) {
+	panic(decodeError{errors.Newf(format, args...)})
+}
+
+// align will discard any bits up to the next byte boundary. The
+// discarded bits must be zero.
+func (br *bitReader) align() {
+	if br.readBits(br.nbits%8) != 0 {
+		fail("invalid padding bits")
+	}
+}
+
+// fill will try to buffer at least n bits. It returns false if the
+// input ended first.
+func (br *bitReader) fill(n uint) bool {
+	var b byte
+	var e error
+
+	for br.nbits < n {
+		if b, e = br.r.ReadByte(); e != nil {
+			if goerrors.Is(e, io.EOF) {
+				return false
+			}
+
+			panic(decodeError{e})
+		}
+
+		br.bits |= uint64(b) << br.nbits
+		br.nbits += 8
+	}
+
+	return true
+}
+
+// peek will return the next n bits w/o consuming them. Missing bits
+// at the end of the input are zero.
+func (br *bitReader) peek(n uint) uint32 {
+	br.fill(n)
+	return uint32(br.bits & (1<<n - 1))
+}
+
+// readBits will consume and return the next n bits (n <= 32).
+func (br *bitReader) readBits(n uint) uint32 {
+	var v uint32
+
+	if n == 0 {
+		return 0
+	}
+
+	v = br.peek(n)
+	br.skip(n)
+
+	return v
+}
+
+// skip will consume n bits, which must be available.
+func (br *bitReader) skip(n uint) {
+	if !br.fill(n) {
+		panic(decodeError{io.ErrUnexpectedEOF})
+	}
+
+	br.bits >>= n
+	br.nbits -= n
+}
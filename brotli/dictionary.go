@@ -0,0 +1,260 @@
+package brotli
+
+import _ "embed"
+
+// Word transform types (RFC 7932 section 8).
+const (
+	identity uint8 = iota
+	omitLast1
+	omitLast2
+	omitLast3
+	omitLast4
+	omitLast5
+	omitLast6
+	omitLast7
+	omitLast8
+	omitLast9
+	uppercaseFirst
+	uppercaseAll
+	omitFirst1
+	omitFirst2
+	omitFirst3
+	omitFirst4
+	omitFirst5
+	omitFirst6
+	omitFirst7
+	omitFirst8
+	omitFirst9
+)
+
+// Static dictionary word lengths.
+const (
+	maxWordLength int = 24
+	minWordLength int = 4
+)
+
+// dictionary is the static dictionary (RFC 7932 appendix A). Its
+// SHA-256 is
+// 20e42eb1b511c21806d4d227d07e5dd06877d8ce7b3a817f378f313653f35c70.
+//
+//go:embed dictionary.bin
+var dictionary string
+
+// dictionaryBits is the number of bits of the word index, by word
+// length.
+var dictionaryBits = [maxWordLength + 1]uint{
+	0, 0, 0, 0, 10, 10, 11, 11, 10, 10, 10, 10, 10,
+	9, 9, 8, 7, 7, 8, 7, 7, 6, 6, 5, 5,
+}
+
+// dictionaryOffsets is the offset of the first word, by word length.
+var dictionaryOffsets = [maxWordLength + 1]int{
+	0, 0, 0, 0, 0, 4096, 9216, 21504, 35840, 44032, 53248, 63488,
+	74752, 87040, 93696, 100864, 104704, 106752, 108928, 113536,
+	115968, 118528, 119872, 121280, 122016,
+}
+
+// transforms is the list of word transforms (RFC 7932 appendix B).
+var transforms = [...]struct {
+	prefix string
+	kind   uint8
+	suffix string
+}{
+	{"", identity, ""},
+	{"", identity, " "},
+	{" ", identity, " "},
+	{"", omitFirst1, ""},
+	{"", uppercaseFirst, " "},
+	{"", identity, " the "},
+	{" ", identity, ""},
+	{"s ", identity, " "},
+	{"", identity, " of "},
+	{"", uppercaseFirst, ""},
+	{"", identity, " and "},
+	{"", omitFirst2, ""},
+	{"", omitLast1, ""},
+	{", ", identity, " "},
+	{"", identity, ", "},
+	{" ", uppercaseFirst, " "},
+	{"", identity, " in "},
+	{"", identity, " to "},
+	{"e ", identity, " "},
+	{"", identity, "\""},
+	{"", identity, "."},
+	{"", identity, "\">"},
+	{"", identity, "\n"},
+	{"", omitLast3, ""},
+	{"", identity, "]"},
+	{"", identity, " for "},
+	{"", omitFirst3, ""},
+	{"", omitLast2, ""},
+	{"", identity, " a "},
+	{"", identity, " that "},
+	{" ", uppercaseFirst, ""},
+	{"", identity, ". "},
+	{".", identity, ""},
+	{" ", identity, ", "},
+	{"", omitFirst4, ""},
+	{"", identity, " with "},
+	{"", identity, "'"},
+	{"", identity, " from "},
+	{"", identity, " by "},
+	{"", omitFirst5, ""},
+	{"", omitFirst6, ""},
+	{" the ", identity, ""},
+	{"", omitLast4, ""},
+	{"", identity, ". The "},
+	{"", uppercaseAll, ""},
+	{"", identity, " on "},
+	{"", identity, " as "},
+	{"", identity, " is "},
+	{"", omitLast7, ""},
+	{"", omitLast1, "ing "},
+	{"", identity, "\n\t"},
+	{"", identity, ":"},
+	{" ", identity, ". "},
+	{"", identity, "ed "},
+	{"", omitFirst9, ""},
+	{"", omitFirst7, ""},
+	{"", omitLast6, ""},
+	{"", identity, "("},
+	{"", uppercaseFirst, ", "},
+	{"", omitLast8, ""},
+	{"", identity, " at "},
+	{"", identity, "ly "},
+	{" the ", identity, " of "},
+	{"", omitLast5, ""},
+	{"", omitLast9, ""},
+	{" ", uppercaseFirst, ", "},
+	{"", uppercaseFirst, "\""},
+	{".", identity, "("},
+	{"", uppercaseAll, " "},
+	{"", uppercaseFirst, "\">"},
+	{"", identity, "=\""},
+	{" ", identity, "."},
+	{".com/", identity, ""},
+	{" the ", identity, " of the "},
+	{"", uppercaseFirst, "'"},
+	{"", identity, ". This "},
+	{"", identity, ","},
+	{".", identity, " "},
+	{"", uppercaseFirst, "("},
+	{"", uppercaseFirst, "."},
+	{"", identity, " not "},
+	{" ", identity, "=\""},
+	{"", identity, "er "},
+	{" ", uppercaseAll, " "},
+	{"", identity, "al "},
+	{" ", uppercaseAll, ""},
+	{"", identity, "='"},
+	{"", uppercaseAll, "\""},
+	{"", uppercaseFirst, ". "},
+	{" ", identity, "("},
+	{"", identity, "ful "},
+	{" ", uppercaseFirst, ". "},
+	{"", identity, "ive "},
+	{"", identity, "less "},
+	{"", uppercaseAll, "'"},
+	{"", identity, "est "},
+	{" ", uppercaseFirst, "."},
+	{"", uppercaseAll, "\">"},
+	{" ", identity, "='"},
+	{"", uppercaseFirst, ","},
+	{"", identity, "ize "},
+	{"", uppercaseAll, "."},
+	{"\u00a0", identity, ""},
+	{" ", identity, ","},
+	{"", uppercaseFirst, "=\""},
+	{"", uppercaseAll, "=\""},
+	{"", identity, "ous "},
+	{"", uppercaseAll, ", "},
+	{"", uppercaseFirst, "='"},
+	{" ", uppercaseFirst, ","},
+	{" ", uppercaseAll, "=\""},
+	{" ", uppercaseAll, ", "},
+	{"", uppercaseAll, ","},
+	{"", uppercaseAll, "("},
+	{"", uppercaseAll, ". "},
+	{" ", uppercaseAll, "."},
+	{"", uppercaseAll, "='"},
+	{" ", uppercaseAll, ". "},
+	{" ", uppercaseFirst, "=\""},
+	{" ", uppercaseAll, "='"},
+	{" ", uppercaseFirst, "='"},
+}
+
+// dictionaryWord will return the transformed static dictionary word
+// referenced by a copy of the provided length, or false if invalid.
+func dictionaryWord(length int, wordID int) ([]byte, bool) {
+	var bits uint
+	var n int
+	var out []byte
+	var t int
+	var word []byte
+
+	if (length < minWordLength) || (length > maxWordLength) {
+		return nil, false
+	}
+
+	bits = dictionaryBits[length]
+	t = wordID >> bits
+
+	if t >= len(transforms) {
+		return nil, false
+	}
+
+	n = dictionaryOffsets[length] + (wordID&(1<<bits-1))*length
+	word = []byte(dictionary[n : n+length])
+
+	switch k := transforms[t].kind; {
+	case (k >= omitLast1) && (k <= omitLast9):
+		if n = int(k-omitLast1) + 1; n > len(word) {
+			n = len(word)
+		}
+
+		word = word[:len(word)-n]
+	case (k >= omitFirst1) && (k <= omitFirst9):
+		if n = int(k-omitFirst1) + 1; n > len(word) {
+			n = len(word)
+		}
+
+		word = word[n:]
+	case k == uppercaseFirst:
+		toUpper(word)
+	case k == uppercaseAll:
+		for i := 0; i < len(word); {
+			i += toUpper(word[i:])
+		}
+	}
+
+	out = append(out, transforms[t].prefix...)
+	out = append(out, word...)
+	out = append(out, transforms[t].suffix...)
+
+	return out, true
+}
+
+// toUpper will uppercase the first UTF-8 character of b in place,
+// using the simplified rules of RFC 7932, and return its length.
+func toUpper(b []byte) int {
+	switch {
+	case b[0] < 0xc0:
+		if (b[0] >= 'a') && (b[0] <= 'z') {
+			b[0] ^= 0x20
+		}
+
+		return 1
+	case b[0] < 0xe0:
+		if len(b) > 1 {
+			b[1] ^= 0x20
+		}
+
+		return 2
+	}
+
+	if len(b) > 2 {
+		b[2] ^= 0x05
+	}
+
+	return 3
+}
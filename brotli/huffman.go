@@ -0,0 +1,290 @@
+package brotli
+
+// Huffman lookup table sizes.
+const (
+	maxCodeLength uint = 15
+	tableBits     uint = 8
+)
+
+// codeLengthOrder is the order in which code length code lengths are
+// stored (RFC 7932 section 3.5).
+var codeLengthOrder = [18]int{
+	1, 2, 3, 4, 0, 5, 17, 6, 16, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+}
+
+// codeLengthPrefix maps the next 4 bits of the input to the length
+// (high nibble) and value (low nibble) of the static prefix code
+// used for code length code lengths (RFC 7932 section 3.5).
+var codeLengthPrefix = [16]uint8{
+	0x20, 0x24, 0x23, 0x32, 0x20, 0x24, 0x23, 0x41,
+	0x20, 0x24, 0x23, 0x32, 0x20, 0x24, 0x23, 0x45,
+}
+
+// huffman is a canonical prefix code. Codes up to tableBits long are
+// decoded w/ a lookup table, longer codes one bit at a time.
+type huffman struct {
+	count   [maxCodeLength + 1]uint16
+	single  bool
+	symbols []uint16
+
+	// table entries are the symbol << 4 | code length, or 0 if the
+	// code is longer than tableBits
+	table [1 << tableBits]uint16
+}
+
+// alphabetBits will return the number of bits needed to store any
+// symbol of the alphabet.
+func alphabetBits(alphabetSize int) uint {
+	var n uint
+
+	for ((alphabetSize - 1) >> n) != 0 {
+		n++
+	}
+
+	return n
+}
+
+// newHuffman will return a canonical prefix code for the provided
+// code lengths. A code w/ a single symbol uses 0 bits.
+func newHuffman(lengths []uint8) *huffman {
+	var code int
+	var h *huffman = &huffman{}
+	var next [maxCodeLength + 2]int
+	var offs [maxCodeLength + 2]int
+
+	for _, l := range lengths {
+		if l != 0 {
+			h.count[l]++
+		}
+	}
+
+	for l := uint(1); l <= maxCodeLength; l++ {
+		offs[l+1] = offs[l] + int(h.count[l])
+	}
+
+	h.symbols = make([]uint16, offs[maxCodeLength+1])
+	copy(next[:], offs[:])
+
+	for sym, l := range lengths {
+		if l != 0 {
+			h.symbols[next[l]] = uint16(sym)
+			next[l]++
+		}
+	}
+
+	switch len(h.symbols) {
+	case 0:
+		fail("invalid empty prefix code")
+	case 1:
+		h.single = true
+		return h
+	}
+
+	// Fill lookup table w/ bit-reversed canonical codes
+	for l := uint(1); l <= tableBits; l++ {
+		for _, sym := range h.symbols[offs[l]:offs[l+1]] {
+			for i := reverse(code, l); i < len(h.table); i += 1 << l {
+				h.table[i] = sym<<4 | uint16(l)
+			}
+
+			code++
+		}
+
+		code <<= 1
+	}
+
+	return h
+}
+
+// readPrefixCode will read a simple or complex prefix code for the
+// provided alphabet (RFC 7932 sections 3.4 and 3.5).
+func readPrefixCode(br *bitReader, alphabetSize int) *huffman {
+	var hskip uint32 = br.readBits(2)
+
+	if hskip == 1 {
+		return readSimplePrefixCode(br, alphabetSize)
+	}
+
+	return readComplexPrefixCode(br, alphabetSize, int(hskip))
+}
+
+func readComplexPrefixCode(
+	br *bitReader,
+	alphabetSize int,
+	hskip int,
+) *huffman {
+	var cl *huffman
+	var clLengths [18]uint8
+	var delta int
+	var extra uint
+	var lengths []uint8 = make([]uint8, alphabetSize)
+	var n int
+	var newLen uint8
+	var prev uint8 = 8
+	var repeat int
+	var repeatLen uint8
+	var space int = 32
+	var sym int
+	var v uint8
+
+	// Code length code lengths
+	for i := hskip; i < len(codeLengthOrder); i++ {
+		v = codeLengthPrefix[br.peek(4)]
+		br.skip(uint(v >> 4))
+
+		if clLengths[codeLengthOrder[i]] = v & 0x0f; v&0x0f != 0 {
+			space -= 32 >> (v & 0x0f)
+			n++
+
+			if space <= 0 {
+				break
+			}
+		}
+	}
+
+	if (n != 1) && (space != 0) {
+		fail("invalid code length code")
+	}
+
+	cl = newHuffman(clLengths[:])
+
+	// Symbol code lengths
+	space = 1 << maxCodeLength
+	for (sym < alphabetSize) && (space > 0) {
+		v = uint8(cl.decode(br))
+
+		if v < 16 {
+			repeat = 0
+			lengths[sym] = v
+			sym++
+
+			if v != 0 {
+				prev = v
+				space -= (1 << maxCodeLength) >> v
+			}
+
+			continue
+		}
+
+		extra, newLen = 2, prev
+		if v == 17 {
+			extra, newLen = 3, 0
+		}
+
+		if repeatLen != newLen {
+			repeat = 0
+			repeatLen = newLen
+		}
+
+		delta = repeat
+		if repeat > 0 {
+			repeat = (repeat - 2) << extra
+		}
+
+		repeat += int(br.readBits(extra)) + 3
+		delta = repeat - delta
+
+		if sym+delta > alphabetSize {
+			fail("invalid code length repeat")
+		}
+
+		for i := 0; i < delta; i++ {
+			lengths[sym] = newLen
+			sym++
+		}
+
+		if newLen != 0 {
+			space -= delta << (maxCodeLength - uint(newLen))
+		}
+	}
+
+	if space != 0 {
+		fail("invalid prefix code")
+	}
+
+	return newHuffman(lengths)
+}
+
+func readSimplePrefixCode(br *bitReader, alphabetSize int) *huffman {
+	var bits uint = alphabetBits(alphabetSize)
+	var lengths []uint8 = make([]uint8, alphabetSize)
+	var nsym int = int(br.readBits(2)) + 1
+	var syms [4]int
+
+	for i := 0; i < nsym; i++ {
+		if syms[i] = int(br.readBits(bits)); syms[i] >= alphabetSize {
+			fail("invalid symbol %d", syms[i])
+		}
+
+		if lengths[syms[i]] != 0 {
+			fail("duplicate symbol %d", syms[i])
+		}
+
+		lengths[syms[i]] = 1
+	}
+
+	switch nsym {
+	case 3:
+		lengths[syms[1]] = 2
+		lengths[syms[2]] = 2
+	case 4:
+		if br.readBits(1) == 0 {
+			for _, sym := range syms {
+				lengths[sym] = 2
+			}
+		} else {
+			lengths[syms[1]] = 2
+			lengths[syms[2]] = 3
+			lengths[syms[3]] = 3
+		}
+	}
+
+	return newHuffman(lengths)
+}
+
+// reverse will return the low n bits of v in reverse order.
+func reverse(v int, n uint) int {
+	var r int
+
+	for i := uint(0); i < n; i++ {
+		r = r<<1 | (v>>i)&1
+	}
+
+	return r
+}
+
+// decode will read a single symbol.
+func (h *huffman) decode(br *bitReader) int {
+	var code int
+	var count int
+	var entry uint16
+	var first int
+	var index int
+
+	if h.single {
+		return int(h.symbols[0])
+	}
+
+	if entry = h.table[br.peek(tableBits)]; entry != 0 {
+		br.skip(uint(entry & 0x0f))
+		return int(entry >> 4)
+	}
+
+	// Long code, one bit at a time
+	for l := uint(1); l <= maxCodeLength; l++ {
+		code |= int(br.readBits(1))
+		count = int(h.count[l])
+
+		if code-first < count {
+			return int(h.symbols[index+code-first])
+		}
+
+		index += count
+		first += count
+		first <<= 1
+		code <<= 1
+	}
+
+	fail("invalid prefix code")
+	return 0
+}
@@ -0,0 +1,596 @@
+// Package brotli implements a Brotli (RFC 7932) decoder in pure Go.
+package brotli
+
+import (
+	"bufio"
+	"io"
+)
+
+// Decoder states.
+const (
+	stateInit int = iota
+	stateHeader
+	stateCommand
+	stateInsert
+	stateCopy
+	stateWord
+	stateUncompressed
+	stateDone
+)
+
+// Block categories.
+const (
+	blockLiteral int = iota
+	blockCommand
+	blockDistance
+)
+
+// Reader is an io.Reader that decodes a Brotli stream. Output is
+// produced incrementally, so memory use is bounded by the window size
+// of the stream (at most 16 MiB).
+type Reader struct {
+	blocks [3]blockState
+	br     bitReader
+
+	// Per meta-block prefix codes and context maps
+	cmdCodes  []*huffman
+	cmodes    []uint8
+	distCodes []*huffman
+	distMap   []uint8
+	litCodes  []*huffman
+	litMap    []uint8
+	ndirect   int
+	npostfix  uint
+
+	// Ring buffer of the last 4 distances
+	dists   [4]int
+	distIdx int
+
+	err     error
+	last    bool
+	mlen    int
+	p1      byte
+	p2      byte
+	pending []byte
+	pos     int64
+	state   int
+	window  []byte
+
+	// Current command
+	copyLen  int
+	distance int
+	implicit bool
+	insert   int
+}
+
+// blockState is the block switching state of a category.
+type blockState struct {
+	count     int
+	countCode *huffman
+	ntypes    int
+	prev      int
+	typ       int
+	typeCode  *huffman
+}
+
+// NewReader will return a pointer to a new Reader instance that
+// decodes the provided io.Reader.
+func NewReader(r io.Reader) *Reader {
+	var br io.ByteReader
+	var ok bool
+
+	if br, ok = r.(io.ByteReader); !ok {
+		br = bufio.NewReader(r)
+	}
+
+	return &Reader{br: bitReader{r: br}}
+}
+
+// readVarLenUint8 will read a value in [0, 255] (RFC 7932 section
+// 9.2).
+func readVarLenUint8(br *bitReader) int {
+	var n uint
+
+	if br.readBits(1) == 0 {
+		return 0
+	}
+
+	if n = uint(br.readBits(3)); n == 0 {
+		return 1
+	}
+
+	return 1<<n + int(br.readBits(n))
+}
+
+// decodeDistance will return the distance of the provided distance
+// code (RFC 7932 section 4).
+func (r *Reader) decodeDistance(code int) int {
+	var d int
+	var hcode int
+	var lcode int
+	var nbits uint
+	var offset int
+
+	switch {
+	case code < 4:
+		return r.lastDistance(code)
+	case code < 16:
+		d = r.lastDistance(0)
+		if code >= 10 {
+			d = r.lastDistance(1)
+		}
+
+		// 4: -1, 5: +1, 6: -2, 7: +2, 8: -3, 9: +3, and again
+		if code = (code - 4) % 6; code%2 == 0 {
+			return d - code/2 - 1
+		}
+
+		return d + code/2 + 1
+	case code < 16+r.ndirect:
+		return code - 15
+	}
+
+	code -= r.ndirect + 16
+	nbits = 1 + uint(code>>(r.npostfix+1))
+	hcode = code >> r.npostfix
+	lcode = code & (1<<r.npostfix - 1)
+	offset = ((2 + (hcode & 1)) << nbits) - 4
+
+	return ((offset + int(r.br.readBits(nbits))) << r.npostfix) +
+		lcode + r.ndirect + 1
+}
+
+// emit will write a byte of output to the window.
+func (r *Reader) emit(b byte) {
+	r.window[r.pos&int64(len(r.window)-1)] = b
+	r.pos++
+	r.p2 = r.p1
+	r.p1 = b
+}
+
+// endMetaBlock will move on to the next meta-block, if any.
+func (r *Reader) endMetaBlock() {
+	if r.last {
+		// Padding of the last byte must be zero
+		r.br.align()
+		r.state = stateDone
+
+		return
+	}
+
+	r.state = stateHeader
+}
+
+func (r *Reader) lastDistance(n int) int {
+	return r.dists[(r.distIdx-n)&3]
+}
+
+// nextBlock will decrement the block count of the provided category,
+// switching blocks first if needed, and return the block type.
+func (r *Reader) nextBlock(category int) int {
+	var b *blockState = &r.blocks[category]
+	var typ int
+
+	if b.count == 0 {
+		switch typ = b.typeCode.decode(&r.br); typ {
+		case 0:
+			typ = b.prev
+		case 1:
+			typ = (b.typ + 1) % b.ntypes
+		default:
+			typ -= 2
+		}
+
+		b.prev, b.typ = b.typ, typ
+		b.count = blockCountPrefix[b.countCode.decode(&r.br)].read(&r.br)
+	}
+
+	b.count--
+
+	return b.typ
+}
+
+// Read will decode up to len(b) bytes.
+func (r *Reader) Read(b []byte) (n int, e error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	defer func() {
+		if x := recover(); x != nil {
+			if de, ok := x.(decodeError); ok {
+				r.err = de.error
+				e = r.err
+
+				return
+			}
+
+			panic(x)
+		}
+	}()
+
+	for (n < len(b)) && (r.state != stateDone) {
+		n += r.step(b[n:])
+	}
+
+	if r.state == stateDone {
+		r.err = io.EOF
+	}
+
+	if n > 0 {
+		return n, nil
+	}
+
+	return 0, r.err
+}
+
+// readBlockSwitch will read the block switching codes of a category.
+func (r *Reader) readBlockSwitch(category int) {
+	var b *blockState = &r.blocks[category]
+
+	// Initially, the last type is 0 and the second-to-last is 1. A
+	// single type never switches, as meta-blocks are at most 1<<24
+	// bytes.
+	*b = blockState{
+		count:  1 << 24,
+		ntypes: readVarLenUint8(&r.br) + 1,
+		prev:   1,
+	}
+
+	if b.ntypes < 2 {
+		return
+	}
+
+	b.typeCode = readPrefixCode(&r.br, b.ntypes+2)
+	b.countCode = readPrefixCode(&r.br, len(blockCountPrefix))
+	b.count = blockCountPrefix[b.countCode.decode(&r.br)].read(&r.br)
+}
+
+// readCommand will read an insert-and-copy command.
+func (r *Reader) readCommand() {
+	var code int = r.cmdCodes[r.nextBlock(blockCommand)].decode(&r.br)
+	var cell = commandCells[code>>6]
+
+	r.implicit = (code >> 6) < 2
+	r.insert = insertLengthPrefix[cell.insert+((code>>3)&7)].read(&r.br)
+	r.copyLen = copyLengthPrefix[cell.copy+(code&7)].read(&r.br)
+
+	if r.insert > r.mlen {
+		fail("invalid insert length")
+	}
+
+	r.mlen -= r.insert
+	r.state = stateInsert
+}
+
+// readContextMap will read a context map of the provided size (RFC
+// 7932 section 7.3) and return it w/ the number of prefix codes.
+func (r *Reader) readContextMap(size int) ([]uint8, int) {
+	var code *huffman
+	var m []uint8 = make([]uint8, size)
+	var n int
+	var ntrees int = readVarLenUint8(&r.br) + 1
+	var rlemax int
+	var sym int
+
+	if ntrees < 2 {
+		return m, ntrees
+	}
+
+	if r.br.readBits(1) == 1 {
+		rlemax = int(r.br.readBits(4)) + 1
+	}
+
+	code = readPrefixCode(&r.br, ntrees+rlemax)
+
+	for i := 0; i < size; {
+		switch sym = code.decode(&r.br); {
+		case sym == 0:
+			i++
+		case sym <= rlemax:
+			if n = 1<<sym + int(r.br.readBits(uint(sym))); i+n > size {
+				fail("invalid context map")
+			}
+
+			i += n
+		default:
+			m[i] = uint8(sym - rlemax)
+			i++
+		}
+	}
+
+	// Inverse move-to-front transform
+	if r.br.readBits(1) == 1 {
+		var mtf [256]uint8
+
+		for i := range mtf {
+			mtf[i] = uint8(i)
+		}
+
+		for i, v := range m {
+			m[i] = mtf[v]
+			copy(mtf[1:v+1], mtf[:v])
+			mtf[0] = m[i]
+		}
+	}
+
+	for _, v := range m {
+		if int(v) >= ntrees {
+			fail("invalid context map")
+		}
+	}
+
+	return m, ntrees
+}
+
+// readDistance will read the distance of the current command and
+// queue the copy.
+func (r *Reader) readDistance() {
+	var code int
+	var ctx int = 3
+	var maxDistance int64 = int64(len(r.window) - 16)
+	var ok bool
+	var word []byte
+
+	if r.copyLen <= 4 {
+		ctx = r.copyLen - 2
+	}
+
+	if !r.implicit {
+		code = r.distCodes[r.distMap[r.nextBlock(blockDistance)*4+ctx]].
+			decode(&r.br)
+	}
+
+	if r.distance = r.decodeDistance(code); r.distance <= 0 {
+		fail("invalid distance")
+	}
+
+	if r.pos < maxDistance {
+		maxDistance = r.pos
+	}
+
+	// Static dictionary reference
+	if int64(r.distance) > maxDistance {
+		word, ok = dictionaryWord(
+			r.copyLen,
+			r.distance-int(maxDistance)-1,
+		)
+		if !ok {
+			fail("invalid dictionary reference")
+		} else if len(word) > r.mlen {
+			fail("invalid copy length")
+		}
+
+		r.mlen -= len(word)
+		r.pending = word
+		r.state = stateWord
+
+		return
+	}
+
+	if code != 0 {
+		r.distIdx = (r.distIdx + 1) & 3
+		r.dists[r.distIdx] = r.distance
+	}
+
+	if r.copyLen > r.mlen {
+		fail("invalid copy length")
+	}
+
+	r.mlen -= r.copyLen
+	r.state = stateCopy
+}
+
+// readHeader will read a meta-block header (RFC 7932 section 9.2).
+func (r *Reader) readHeader() {
+	var n int
+	var nibbles uint
+
+	if r.last = r.br.readBits(1) == 1; r.last {
+		// Empty last meta-block
+		if r.br.readBits(1) == 1 {
+			r.br.align()
+			r.state = stateDone
+
+			return
+		}
+	}
+
+	if nibbles = uint(r.br.readBits(2)) + 4; nibbles == 7 {
+		r.skipMetadata()
+		return
+	}
+
+	for i := uint(0); i < nibbles; i++ {
+		// Last nibble can't be 0, unless there are only 4
+		n = int(r.br.readBits(4))
+		if (nibbles > 4) && (i == nibbles-1) && (n == 0) {
+			fail("invalid meta-block length")
+		}
+
+		r.mlen |= n << (4 * i)
+	}
+
+	r.mlen++
+
+	if !r.last && (r.br.readBits(1) == 1) {
+		r.br.align()
+		r.state = stateUncompressed
+
+		return
+	}
+
+	r.readMetaBlock()
+	r.state = stateCommand
+}
+
+// readMetaBlock will read the prefix codes and context maps of a
+// compressed meta-block.
+func (r *Reader) readMetaBlock() {
+	var distAlphabet int
+	var n int
+
+	for i := range r.blocks {
+		r.readBlockSwitch(i)
+	}
+
+	r.npostfix = uint(r.br.readBits(2))
+	r.ndirect = int(r.br.readBits(4)) << r.npostfix
+
+	r.cmodes = make([]uint8, r.blocks[blockLiteral].ntypes)
+	for i := range r.cmodes {
+		r.cmodes[i] = uint8(r.br.readBits(2))
+	}
+
+	r.litMap, n = r.readContextMap(64 * r.blocks[blockLiteral].ntypes)
+	r.litCodes = make([]*huffman, n)
+
+	r.distMap, n = r.readContextMap(4 * r.blocks[blockDistance].ntypes)
+	r.distCodes = make([]*huffman, n)
+
+	for i := range r.litCodes {
+		r.litCodes[i] = readPrefixCode(&r.br, 256)
+	}
+
+	r.cmdCodes = make([]*huffman, r.blocks[blockCommand].ntypes)
+	for i := range r.cmdCodes {
+		r.cmdCodes[i] = readPrefixCode(&r.br, 704)
+	}
+
+	distAlphabet = 16 + r.ndirect + 48<<r.npostfix
+	for i := range r.distCodes {
+		r.distCodes[i] = readPrefixCode(&r.br, distAlphabet)
+	}
+}
+
+// readWindowBits will read the stream header and allocate the window
+// (RFC 7932 section 9.1).
+func (r *Reader) readWindowBits() {
+	var n uint32
+	var wbits uint = 16
+
+	if r.br.readBits(1) == 1 {
+		if n = r.br.readBits(3); n != 0 {
+			wbits = 17 + uint(n)
+		} else if n = r.br.readBits(3); n == 1 {
+			fail("large window brotli is not supported")
+		} else if n != 0 {
+			wbits = 8 + uint(n)
+		} else {
+			wbits = 17
+		}
+	}
+
+	r.dists = [4]int{16, 15, 11, 4}
+	r.distIdx = 3
+	r.window = make([]byte, 1<<wbits)
+	r.state = stateHeader
+}
+
+// skipMetadata will skip a metadata meta-block.
+func (r *Reader) skipMetadata() {
+	var n int
+	var nbytes uint
+	var v int
+
+	if r.br.readBits(1) != 0 {
+		fail("invalid reserved bit")
+	}
+
+	if nbytes = uint(r.br.readBits(2)); nbytes > 0 {
+		for i := uint(0); i < nbytes; i++ {
+			// Last byte can't be 0, unless there is only 1
+			v = int(r.br.readBits(8))
+			if (nbytes > 1) && (i == nbytes-1) && (v == 0) {
+				fail("invalid metadata length")
+			}
+
+			n |= v << (8 * i)
+		}
+
+		n++
+	}
+
+	r.br.align()
+
+	for i := 0; i < n; i++ {
+		r.br.readBits(8)
+	}
+
+	r.endMetaBlock()
+}
+
+// step will advance the decoder, writing up to len(b) bytes of output.
+func (r *Reader) step(b []byte) int {
+	var ctx int
+	var n int
+	var typ int
+
+	switch r.state {
+	case stateInit:
+		r.readWindowBits()
+	case stateHeader:
+		r.mlen = 0
+		r.readHeader()
+	case stateCommand:
+		if r.mlen == 0 {
+			r.endMetaBlock()
+			break
+		}
+
+		r.readCommand()
+	case stateInsert:
+		for ; (r.insert > 0) && (n < len(b)); n++ {
+			typ = r.nextBlock(blockLiteral)
+			ctx = literalContext(r.cmodes[typ], r.p1, r.p2)
+			b[n] = byte(r.litCodes[r.litMap[typ*64+ctx]].decode(&r.br))
+			r.emit(b[n])
+			r.insert--
+		}
+
+		if r.insert > 0 {
+			break
+		}
+
+		// Copy is ignored if the meta-block is complete
+		if r.mlen == 0 {
+			r.endMetaBlock()
+			break
+		}
+
+		r.readDistance()
+	case stateCopy:
+		for ; (r.copyLen > 0) && (n < len(b)); n++ {
+			b[n] = r.window[(r.pos-int64(r.distance))&
+				int64(len(r.window)-1)]
+			r.emit(b[n])
+			r.copyLen--
+		}
+
+		if r.copyLen == 0 {
+			r.state = stateCommand
+		}
+	case stateWord:
+		for ; (len(r.pending) > 0) && (n < len(b)); n++ {
+			b[n] = r.pending[0]
+			r.emit(b[n])
+			r.pending = r.pending[1:]
+		}
+
+		if len(r.pending) == 0 {
+			r.state = stateCommand
+		}
+	case stateUncompressed:
+		for ; (r.mlen > 0) && (n < len(b)); n++ {
+			b[n] = byte(r.br.readBits(8))
+			r.emit(b[n])
+			r.mlen--
+		}
+
+		if r.mlen == 0 {
+			r.endMetaBlock()
+		}
+	}
+
+	return n
+}
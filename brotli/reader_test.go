@@ -0,0 +1,282 @@
+package brotli
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// reference holds the streams from the reference implementation's
+// test data, and edge cases that libbrotlidec decodes successfully.
+var reference = map[string]struct {
+	in  []byte
+	out string
+}{
+	"empty":    {[]byte{0x06}, ""},
+	"metadata": {[]byte{0x0c, 0x03}, ""},
+	"x":        {[]byte{0x0b, 0x00, 0x80, 0x58, 0x03}, "X"},
+	"10x10y": {
+		[]byte{
+			0x1b, 0x13, 0x00, 0x00, 0xa4, 0xb0, 0xb2, 0xea, 0x81, 0x47,
+			0x02, 0x8a,
+		},
+		"XXXXXXXXXXYYYYYYYYYY",
+	},
+	"64x": {
+		[]byte{
+			0x1b, 0x3f, 0x00, 0x00, 0x24, 0xb0, 0xe2, 0x99, 0x80, 0x12,
+		},
+		strings.Repeat("X", 64),
+	},
+	"quickfox": {
+		append(
+			append(
+				[]byte{0x0b, 0x15, 0x80},
+				"The quick brown fox jumps over the lazy dog"...,
+			),
+			0x03,
+		),
+		"The quick brown fox jumps over the lazy dog",
+	},
+	"ukkonooa": {
+		[]byte{
+			0x1b, 0x76, 0x00, 0x00, 0x14, 0x4a, 0xac, 0x9b, 0x7a, 0xbd,
+			0xe1, 0x97, 0x9d, 0x7f, 0x8e, 0xc2, 0x82, 0x36, 0x0e, 0x9c,
+			0xe0, 0x90, 0x03, 0xf7, 0x8b, 0x9e, 0x38, 0xe6, 0xb6, 0x00,
+			0xab, 0xc3, 0xca, 0xa0, 0xc2, 0xda, 0x66, 0x36, 0xdc, 0xcd,
+			0x80, 0x8d, 0x2e, 0x21, 0xd7, 0x6e, 0xe3, 0xea, 0x4c, 0xb8,
+			0xf0, 0xd2, 0xb8, 0xc7, 0xc2, 0x70, 0x4d, 0x3a, 0xf0, 0x69,
+			0x7e, 0xa1, 0xb8, 0x45, 0x73, 0xab, 0xc4, 0x57, 0x1e,
+		},
+		"ukko nooa, ukko nooa oli kunnon mies, kun han meni " +
+			"saunaan, pisti laukun naulaan, ukko nooa, ukko nooa " +
+			"oli kunnon mies.",
+	},
+
+	// Other window sizes
+	"empty lgwin 17": {[]byte{0x81, 0x01}, ""},
+	"empty lgwin 21": {[]byte{0xa1, 0x01}, ""},
+	"empty lgwin 22": {[]byte{0xb1, 0x01}, ""},
+
+	// Anything after the last meta-block is not read
+	"trailing data": {[]byte{0x0b, 0x00, 0x80, 0x58, 0x03, 0xff}, "X"},
+}
+
+// decode will decode the provided stream, one byte at a time if
+// slow, to exercise the streaming state machine.
+func decode(in []byte, slow bool) ([]byte, error) {
+	var r io.Reader = bytes.NewReader(in)
+
+	if slow {
+		r = iotest.OneByteReader(r)
+	}
+
+	return io.ReadAll(NewReader(r))
+}
+
+// readTestdata will return the reference streams in testdata (named
+// <original>.q<quality>w<lgwin>.br) and their decoded contents. The
+// streams were created w/ the reference encoder (libbrotlienc).
+func readTestdata(t *testing.T) map[string][2][]byte {
+	var e error
+	var in []byte
+	var orig string
+	var out []byte
+	var paths []string
+	var vectors = map[string][2][]byte{}
+
+	if paths, e = filepath.Glob(filepath.Join("testdata", "*.br")); e != nil {
+		t.Fatal(e)
+	}
+
+	for _, path := range paths {
+		if in, e = os.ReadFile(path); e != nil {
+			t.Fatal(e)
+		}
+
+		// Strip .br and the .q<quality>w<lgwin> extensions
+		orig = strings.TrimSuffix(path, ".br")
+		orig = strings.TrimSuffix(orig, filepath.Ext(orig))
+
+		if out, e = os.ReadFile(orig); e != nil {
+			t.Fatal(e)
+		}
+
+		vectors[filepath.Base(path)] = [2][]byte{in, out}
+	}
+
+	if len(vectors) == 0 {
+		t.Fatal("no test vectors found")
+	}
+
+	return vectors
+}
+
+func TestCorrupt(t *testing.T) {
+	var in []byte
+	var vectors map[string][2][]byte = readTestdata(t)
+
+	// Any error is fine, but a corrupt stream must not panic
+	for name, v := range vectors {
+		if !strings.HasPrefix(name, "license.txt.q5") {
+			continue
+		}
+
+		// Headers and prefix codes are at the start
+		for i := 0; i < len(v[0]); i += 1 + i/64 {
+			for _, bit := range []byte{0x01, 0x10, 0x80} {
+				in = append([]byte(nil), v[0]...)
+				in[i] ^= bit
+
+				decode(in, false)
+			}
+		}
+	}
+
+	// Hand crafted invalid streams, all rejected by libbrotlidec
+	for name, in := range map[string][]byte{
+		"empty input":     {},
+		"large window":    {0x11, 0x00},
+		"padding bits":    {0x0b, 0x00, 0x80, 0x58, 0x0b},
+		"padding in last": {0x0b, 0x00, 0x80, 0x58, 0x07},
+		"reserved bit":    {0x1c, 0x00},
+		"truncated":       {0x0b, 0x00, 0x80, 0x58},
+		"truncated 64x": {
+			0x1b, 0x3f, 0x00, 0x00, 0x24, 0xb0, 0xe2, 0x99, 0x80,
+		},
+	} {
+		if _, e := decode(in, false); e == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+// TestBitFlips checks every single bit flip of the small reference
+// streams, and of the headers of a larger one, against the verdicts
+// of libbrotlidec in testdata/flips.txt. Each line is the stream, the
+// byte and bit flipped, and either "error" or the CRC-32 and length
+// of the output.
+func TestBitFlips(t *testing.T) {
+	var b []byte
+	var bit int
+	var e error
+	var expected string
+	var f []string
+	var got string
+	var in []byte
+	var lgwin10 []byte
+	var n int
+	var out []byte
+
+	if b, e = os.ReadFile("testdata/flips.txt"); e != nil {
+		t.Fatal(e)
+	}
+
+	lgwin10, e = os.ReadFile("testdata/license.txt.q0w10.br")
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		if f = strings.Fields(line); len(f) < 4 {
+			t.Fatalf("malformed line %q", line)
+		}
+
+		if in = reference[f[0]].in; f[0] == "license.txt.q0w10.br" {
+			in = lgwin10
+		}
+
+		in = append([]byte(nil), in...)
+		n, _ = strconv.Atoi(f[1])
+		bit, _ = strconv.Atoi(f[2])
+		in[n] ^= 1 << bit
+
+		got = "error"
+		if out, e = decode(in, false); e == nil {
+			got = fmt.Sprintf("%08x %d", crc32.ChecksumIEEE(out), len(out))
+		}
+
+		if expected = strings.Join(f[3:], " "); got != expected {
+			t.Errorf("%s: got %s, expected %s", line, got, expected)
+		}
+	}
+}
+
+func TestReader(t *testing.T) {
+	var e error
+	var out []byte
+
+	for name, test := range reference {
+		for _, slow := range []bool{false, true} {
+			if out, e = decode(test.in, slow); e != nil {
+				t.Errorf("%s: %s", name, e)
+			} else if string(out) != test.out {
+				t.Errorf("%s: got %q, expected %q", name, out, test.out)
+			}
+		}
+	}
+
+	for name, v := range readTestdata(t) {
+		for _, slow := range []bool{false, true} {
+			if out, e = decode(v[0], slow); e != nil {
+				t.Errorf("%s: %s", name, e)
+			} else if !bytes.Equal(out, v[1]) {
+				t.Errorf("%s: output mismatch", name)
+			}
+		}
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	var e error
+
+	for name, v := range readTestdata(t) {
+		for i := 0; i < len(v[0]); i += 1 + i/64 {
+			if _, e = decode(v[0][:i], false); e == nil {
+				t.Errorf("%s: no error for %d bytes", name, i)
+				break
+			}
+		}
+	}
+}
+
+// TestWindowSize checks that copies can't reach further back than the
+// window allows. The stream was encoded w/ a 2 KiB window (lgwin 11),
+// so it only decodes correctly w/ a big enough window.
+func TestWindowSize(t *testing.T) {
+	var e error
+	var in []byte
+	var out []byte
+	var want []byte
+
+	if in, e = os.ReadFile("testdata/window.bin.q11w11.br"); e != nil {
+		t.Fatal(e)
+	}
+
+	if want, e = os.ReadFile("testdata/window.bin"); e != nil {
+		t.Fatal(e)
+	}
+
+	// WBITS is the low 7 bits of the first byte: 11 is 0x31
+	if in[0]&0x7f != 0x31 {
+		t.Fatalf("unexpected WBITS %#x", in[0]&0x7f)
+	}
+
+	for wbits, hdr := range map[int]byte{10: 0x21, 11: 0x31, 12: 0x41} {
+		in[0] = in[0]&0x80 | hdr
+		out, e = decode(in, false)
+
+		if (wbits < 11) && (e == nil) && bytes.Equal(out, want) {
+			t.Errorf("lgwin %d: decoded beyond the window", wbits)
+		} else if (wbits >= 11) && ((e != nil) || !bytes.Equal(out, want)) {
+			t.Errorf("lgwin %d: failed to decode: %v", wbits, e)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package core
+
+// Backend is the interface implemented by the libraries capable of
+// sending a Request, such as WinHTTP and WinINet. A Backend is
+// responsible for a single round trip. Anything that should behave
+// the same regardless of library (e.g. cookie handling) belongs in
+// the Client. If a Backend holds resources (e.g. a session handle),
+// it should also implement io.Closer, which is called by
+// Client.Close. If a Backend keeps idle connections, it should also
+// implement CloseIdleConnections(), which is called by
+// Client.CloseIdleConnections.
+type Backend interface {
+	// Send will send the Request and return the Response. The
+	// Client is provided so its options (Timeout, TLSClientConfig,
+	// etc...) can be honored.
+	Send(c *Client, r *Request) (*Response, error)
+}
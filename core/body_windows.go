@@ -0,0 +1,132 @@
+package core
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mjwhitta/win/errors"
+)
+
+// handleBody is an io.ReadCloser that lazily reads a response body
+// from a WinHTTP or WinINet request handle. It owns the request
+// handle and its pooled connection handle from the moment they are
+// opened, so they are released exactly once, whether by Close, a
+// failed request, or the Request's context being done.
+type handleBody struct {
+	avail  func(hndl uintptr, n *int64) error
+	close  func(hndl uintptr) error
+	closed atomic.Bool
+	ctx    context.Context
+	once   sync.Once
+
+	// put returns the connection handle to the pool, which may close
+	// it, if not reusable
+	put func(reuse bool)
+
+	read    func(hndl uintptr, b *[]byte, n int64, read *int64) error
+	reqHndl uintptr
+	stop    func() bool
+}
+
+// abort will stop watching the context and release the underlying
+// handles w/o reusing the connection handle, e.g. after a failed
+// request.
+func (b *handleBody) abort() {
+	if b.stop != nil {
+		b.stop()
+	}
+
+	b.release(false)
+}
+
+// Close will stop watching the context and release the underlying
+// handles. It is safe to call Close while a Read is blocked, which
+// will abort the Read.
+func (b *handleBody) Close() error {
+	if b.stop != nil {
+		b.stop()
+	}
+
+	return b.release(true)
+}
+
+// err will return e, unless the context is done, in which case the
+// context's error is returned instead.
+func (b *handleBody) err(e error) error {
+	if b.ctx.Err() != nil {
+		return errors.Newf("request aborted: %w", b.ctx.Err())
+	}
+
+	return e
+}
+
+// Read will read up to len(p) bytes of whatever data is currently
+// available, blocking until some is.
+func (b *handleBody) Read(p []byte) (int, error) {
+	var chunk []byte
+	var chunkLen int64
+	var e error
+	var n int64
+
+	if b.closed.Load() {
+		return 0, b.err(errors.New("read on closed response body"))
+	}
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	// Get next chunk size
+	if e = b.avail(b.reqHndl, &chunkLen); e != nil {
+		e = errors.Newf("failed to query data available: %w", e)
+		return 0, b.err(e)
+	}
+
+	// Stop, if finished
+	if chunkLen == 0 {
+		return 0, io.EOF
+	}
+
+	if chunkLen > int64(len(p)) {
+		chunkLen = int64(len(p))
+	}
+
+	// Read next chunk
+	if e = b.read(b.reqHndl, &chunk, chunkLen, &n); e != nil {
+		return 0, b.err(errors.Newf("failed to read data: %w", e))
+	}
+
+	return copy(p, chunk[:n]), nil
+}
+
+func (b *handleBody) release(reuse bool) error {
+	var e error
+
+	b.once.Do(
+		func() {
+			b.closed.Store(true)
+
+			if e = b.close(b.reqHndl); e != nil {
+				e = errors.Newf("failed to close request: %w", e)
+				reuse = false
+			}
+
+			b.put(reuse)
+		},
+	)
+
+	return e
+}
+
+// watch will release the handles as soon as the context is done,
+// which aborts any blocking call using them.
+func (b *handleBody) watch() {
+	b.stop = watchContext(
+		b.ctx,
+		func() {
+			b.release(false)
+		},
+	)
+}
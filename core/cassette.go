@@ -0,0 +1,354 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/mjwhitta/win/errors"
+)
+
+// Cassette records request/response pairs to a JSON file, or replays
+// them, so code using a Client can be tested w/o a live server. It is
+// attached as a Middleware:
+//
+//	var cas *core.Cassette
+//	var e error
+//
+//	cas, e = core.NewCassette("testdata/api.json", core.CassetteReplay)
+//	c.Middleware = append(c.Middleware, cas.Middleware)
+//
+// In CassetteRecord mode, every round trip is sent and recorded, and
+// Save must be called to write the file. In CassetteReplay mode,
+// nothing is sent. Each Request is served by the first unused
+// Interaction that satisfies every Matcher, and unmatched Requests
+// fail with an error wrapping ErrCassetteNoMatch.
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+
+	// Matchers decide which recorded Interaction serves a Request
+	// in CassetteReplay mode. If nil, DefaultMatchers is used.
+	Matchers []Matcher `json:"-"`
+
+	mode  CassetteMode
+	mutex sync.Mutex
+	path  string
+	used  map[*Interaction]bool
+}
+
+// CassetteMode is the mode of a Cassette.
+type CassetteMode int
+
+// Valid CassetteMode values.
+const (
+	CassetteRecord CassetteMode = iota + 1
+	CassetteReplay
+)
+
+// CassetteRequest is a recorded Request.
+type CassetteRequest struct {
+	Body         string    `json:"body,omitempty"`
+	BodyEncoding string    `json:"bodyEncoding,omitempty"`
+	Cookies      []*Cookie `json:"cookies,omitempty"`
+	Headers      Header    `json:"headers,omitempty"`
+	Method       string    `json:"method"`
+	URL          string    `json:"url"`
+}
+
+// CassetteResponse is a recorded Response.
+type CassetteResponse struct {
+	Body         string    `json:"body,omitempty"`
+	BodyEncoding string    `json:"bodyEncoding,omitempty"`
+	Cookies      []*Cookie `json:"cookies,omitempty"`
+	Headers      Header    `json:"headers,omitempty"`
+	Proto        string    `json:"proto,omitempty"`
+	Status       string    `json:"status"`
+	StatusCode   int       `json:"statusCode"`
+}
+
+// Interaction is a single recorded round trip.
+type Interaction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+// Matcher will return true if the Request (with its body already
+// read) matches the recorded request.
+type Matcher func(r *Request, body []byte, rec *CassetteRequest) bool
+
+// DefaultMatchers match by method and URL.
+var DefaultMatchers = []Matcher{MatchMethod, MatchURL}
+
+// ErrCassetteNoMatch is returned when a Cassette in CassetteReplay
+// mode has no Interaction for a Request.
+var ErrCassetteNoMatch = errors.New("no matching cassette interaction")
+
+// NewCassette will return a pointer to a new Cassette instance that
+// uses the provided file. In CassetteReplay mode, the file is loaded
+// and must exist.
+func NewCassette(path string, mode CassetteMode) (*Cassette, error) {
+	var b []byte
+	var c *Cassette = &Cassette{
+		mode: mode,
+		path: path,
+		used: map[*Interaction]bool{},
+	}
+	var e error
+
+	switch mode {
+	case CassetteRecord:
+		return c, nil
+	case CassetteReplay:
+	default:
+		return nil, errors.Newf("invalid cassette mode %d", mode)
+	}
+
+	if b, e = os.ReadFile(path); e != nil {
+		return nil, errors.Newf("failed to read %s: %w", path, e)
+	}
+
+	if e = json.Unmarshal(b, c); e != nil {
+		return nil, errors.Newf("failed to parse %s: %w", path, e)
+	}
+
+	return c, nil
+}
+
+// MatchBody is a Matcher that compares request bodies.
+func MatchBody(r *Request, body []byte, rec *CassetteRequest) bool {
+	var b []byte
+	var e error
+
+	if b, e = decodeBody(rec.Body, rec.BodyEncoding); e != nil {
+		return false
+	}
+
+	return bytes.Equal(body, b)
+}
+
+// MatchHeaders will return a Matcher that compares the values of the
+// named request headers.
+func MatchHeaders(names ...string) Matcher {
+	return func(r *Request, body []byte, rec *CassetteRequest) bool {
+		var got []string
+		var want []string
+
+		for _, name := range names {
+			got = r.Headers.Values(name)
+			want = rec.Headers.Values(name)
+
+			if strings.Join(got, "\n") != strings.Join(want, "\n") {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// MatchMethod is a Matcher that compares request methods.
+func MatchMethod(r *Request, body []byte, rec *CassetteRequest) bool {
+	return strings.EqualFold(r.Method, rec.Method)
+}
+
+// MatchURL is a Matcher that compares request URLs.
+func MatchURL(r *Request, body []byte, rec *CassetteRequest) bool {
+	return r.URL == rec.URL
+}
+
+// readBody will read the Request body for matching or recording. If
+// the body is still to be sent, it is replaced with a copy.
+func readBody(r *Request, send bool) (*Request, []byte, error) {
+	var b []byte
+	var e error
+
+	if r.Body == nil {
+		return r, nil, nil
+	}
+
+	if b, e = io.ReadAll(r.Body); e != nil {
+		return nil, nil, errors.Newf("failed to read body: %w", e)
+	}
+
+	if send {
+		r = r.Clone(r.Context())
+		r.Body = bytes.NewReader(b)
+		r.GetBody = func() (io.Reader, error) {
+			return bytes.NewReader(b), nil
+		}
+		r.ContentLength = int64(len(b))
+	}
+
+	return r, b, nil
+}
+
+// match will return the first unused Interaction matching the
+// Request, marking it used.
+func (c *Cassette) match(r *Request, body []byte) *Interaction {
+	var matchers []Matcher = c.Matchers
+	var ok bool
+
+	if matchers == nil {
+		matchers = DefaultMatchers
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, i := range c.Interactions {
+		if c.used[i] {
+			continue
+		}
+
+		ok = true
+		for _, m := range matchers {
+			if ok = m(r, body, &i.Request); !ok {
+				break
+			}
+		}
+
+		if ok {
+			c.used[i] = true
+			return i
+		}
+	}
+
+	return nil
+}
+
+// Middleware will record or replay each round trip, see Cassette.
+func (c *Cassette) Middleware(next RoundTripFunc) RoundTripFunc {
+	if c.mode == CassetteReplay {
+		return c.replay
+	}
+
+	return func(r *Request) (*Response, error) {
+		return c.record(next, r)
+	}
+}
+
+// record will send the Request and store the round trip. Both bodies
+// are buffered in memory.
+func (c *Cassette) record(
+	next RoundTripFunc,
+	r *Request,
+) (*Response, error) {
+	var e error
+	var i *Interaction = &Interaction{}
+	var reqBody []byte
+	var res *Response
+	var resBody []byte
+
+	if r, reqBody, e = readBody(r, true); e != nil {
+		return nil, e
+	}
+
+	if res, e = next(r); e != nil {
+		return nil, e
+	}
+
+	if res.Body != nil {
+		resBody, e = io.ReadAll(res.Body)
+		res.Body.Close()
+
+		if e != nil {
+			return nil, errors.Newf("failed to read body: %w", e)
+		}
+	}
+
+	res.Body = io.NopCloser(bytes.NewReader(resBody))
+
+	i.Request = CassetteRequest{
+		Cookies: r.Cookies(),
+		Headers: r.Headers.Clone(),
+		Method:  r.Method,
+		URL:     r.URL,
+	}
+	i.Request.Body, i.Request.BodyEncoding = encodeBody(reqBody)
+
+	i.Response = CassetteResponse{
+		Cookies:    res.Cookies(),
+		Headers:    res.Header.Clone(),
+		Proto:      res.Proto,
+		Status:     res.Status,
+		StatusCode: res.StatusCode,
+	}
+	i.Response.Body, i.Response.BodyEncoding = encodeBody(resBody)
+
+	c.mutex.Lock()
+	c.Interactions = append(c.Interactions, i)
+	c.mutex.Unlock()
+
+	return res, nil
+}
+
+// replay will serve the Request from the first matching Interaction.
+func (c *Cassette) replay(r *Request) (*Response, error) {
+	var body []byte
+	var e error
+	var i *Interaction
+	var res *Response
+
+	if _, body, e = readBody(r, false); e != nil {
+		return nil, e
+	}
+
+	if i = c.match(r, body); i == nil {
+		return nil, errors.Newf(
+			"%w: %s %s",
+			ErrCassetteNoMatch,
+			r.Method,
+			r.URL,
+		)
+	}
+
+	body, e = decodeBody(i.Response.Body, i.Response.BodyEncoding)
+	if e != nil {
+		return nil, e
+	}
+
+	res = &Response{
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Header:        i.Response.Headers.Clone(),
+		Proto:         i.Response.Proto,
+		Status:        i.Response.Status,
+		StatusCode:    i.Response.StatusCode,
+	}
+
+	if res.Header == nil {
+		res.Header = Header{}
+	}
+
+	if res.Proto != "" {
+		res.ProtoMajor, res.ProtoMinor, _ = http.ParseHTTPVersion(
+			res.Proto,
+		)
+	}
+
+	for _, cookie := range i.Response.Cookies {
+		res.AddCookie(cookie)
+	}
+
+	return res, nil
+}
+
+// Save will atomically write the Cassette to its file.
+func (c *Cassette) Save() error {
+	var b []byte
+	var e error
+
+	c.mutex.Lock()
+	b, e = json.MarshalIndent(c, "", "  ")
+	c.mutex.Unlock()
+
+	if e != nil {
+		return errors.Newf("failed to save %s: %w", c.path, e)
+	}
+
+	return writeFileAtomic(c.path, append(b, '\n'))
+}
@@ -0,0 +1,198 @@
+package core
+
+import (
+	"bytes"
+	goerrors "errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCassette(t *testing.T) {
+	var b Backend
+	var binary []byte = []byte{0x00, 0xff, 0xfe}
+	var c *Client
+	var cas *Cassette
+	var dir string = t.TempDir()
+	var e error
+	var files []os.DirEntry
+	var out []byte
+	var path string = filepath.Join(dir, "cassette.json")
+	var res *Response
+	var srv *httptest.Server
+
+	srv = httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/binary" {
+					w.Write(binary)
+					return
+				}
+
+				http.SetCookie(w, &http.Cookie{Name: "a", Value: "1"})
+				w.Header().Set("X-Method", r.Method)
+				w.WriteHeader(http.StatusCreated)
+				io.Copy(w, r.Body)
+			},
+		),
+	)
+
+	if b, e = NewStd("test", ""); e != nil {
+		t.Fatal(e)
+	}
+
+	// Record
+	if cas, e = NewCassette(path, CassetteRecord); e != nil {
+		t.Fatal(e)
+	}
+
+	c = NewClient(b)
+	c.Middleware = []Middleware{cas.Middleware}
+
+	for _, body := range []string{"first", "second"} {
+		if res, e = c.Post(srv.URL, "", []byte(body)); e != nil {
+			t.Fatal(e)
+		}
+
+		// The body is still sent and returned while recording
+		out, _ = io.ReadAll(res.Body)
+		res.Body.Close()
+
+		if string(out) != body {
+			t.Errorf("got %q, expected %q", out, body)
+		}
+	}
+
+	if res, e = c.Get(srv.URL + "/binary"); e != nil {
+		t.Fatal(e)
+	}
+	res.Body.Close()
+
+	if e = cas.Save(); e != nil {
+		t.Fatal(e)
+	}
+
+	// No temp files are left behind
+	if files, e = os.ReadDir(dir); e != nil {
+		t.Fatal(e)
+	} else if len(files) != 1 {
+		t.Errorf("got files %v", files)
+	}
+
+	srv.Close()
+
+	// Replay w/o a server
+	if cas, e = NewCassette(path, CassetteReplay); e != nil {
+		t.Fatal(e)
+	}
+
+	cas.Matchers = append(DefaultMatchers, MatchBody)
+
+	c = NewClient(b)
+	c.Middleware = []Middleware{cas.Middleware}
+
+	// Out of order, matched by body
+	for _, body := range []string{"second", "first"} {
+		if res, e = c.Post(srv.URL, "", []byte(body)); e != nil {
+			t.Fatal(e)
+		}
+
+		out, _ = io.ReadAll(res.Body)
+		res.Body.Close()
+
+		if string(out) != body {
+			t.Errorf("got %q, expected %q", out, body)
+		}
+
+		if res.StatusCode != http.StatusCreated {
+			t.Errorf("got status %d, expected 201", res.StatusCode)
+		} else if v := res.Header.Get("X-Method"); v != MethodPost {
+			t.Errorf("got X-Method %q, expected POST", v)
+		} else if len(res.Cookies()) != 1 {
+			t.Errorf("got cookies %v, expected [a=1]", res.Cookies())
+		}
+	}
+
+	// Binary bodies round trip
+	if res, e = c.Get(srv.URL + "/binary"); e != nil {
+		t.Fatal(e)
+	}
+
+	out, _ = io.ReadAll(res.Body)
+	res.Body.Close()
+
+	if !bytes.Equal(out, binary) {
+		t.Errorf("got %x, expected %x", out, binary)
+	}
+
+	// Each Interaction is only used once
+	_, e = c.Post(srv.URL, "", []byte("first"))
+	if !goerrors.Is(e, ErrCassetteNoMatch) {
+		t.Errorf("got %v, expected ErrCassetteNoMatch", e)
+	}
+}
+
+func TestCassetteErrors(t *testing.T) {
+	var e error
+	var path string = filepath.Join(t.TempDir(), "cassette.json")
+
+	if _, e = NewCassette(path, CassetteReplay); e == nil {
+		t.Error("expected error for missing file")
+	}
+
+	if _, e = NewCassette(path, 0); e == nil {
+		t.Error("expected error for invalid mode")
+	}
+
+	os.WriteFile(path, []byte("{"), 0o600)
+
+	if _, e = NewCassette(path, CassetteReplay); e == nil {
+		t.Error("expected error for malformed JSON")
+	}
+}
+
+func TestMatchers(t *testing.T) {
+	var r *Request = NewRequest(MethodGet, "http://example.com/a")
+	var rec *CassetteRequest = &CassetteRequest{
+		Body:         "AP8=",
+		BodyEncoding: "base64",
+		Headers:      Header{},
+		Method:       "get",
+		URL:          "http://example.com/a",
+	}
+
+	r.Headers.Add("X-Test", "a")
+	r.Headers.Add("X-Test", "b")
+	rec.Headers.Add("X-Test", "a")
+
+	if !MatchMethod(r, nil, rec) || !MatchURL(r, nil, rec) {
+		t.Error("expected method and URL to match")
+	}
+
+	if !MatchBody(r, []byte{0x00, 0xff}, rec) {
+		t.Error("expected base64 body to match")
+	}
+
+	if MatchBody(r, []byte("other"), rec) {
+		t.Error("expected body mismatch")
+	}
+
+	if MatchHeaders("X-Test")(r, nil, rec) {
+		t.Error("expected header mismatch")
+	}
+
+	rec.Headers.Add("X-Test", "b")
+
+	if !MatchHeaders("X-Test", "X-Missing")(r, nil, rec) {
+		t.Error("expected headers to match")
+	}
+
+	r.URL += "?b=1"
+
+	if MatchURL(r, nil, rec) {
+		t.Error("expected URL mismatch")
+	}
+}
@@ -0,0 +1,301 @@
+package core
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/mjwhitta/win/errors"
+)
+
+// Client is a struct containing relevant metadata to make HTTP
+// requests. A Client is safe for concurrent use by multiple
+// goroutines, so it should be created once and reused. Its fields
+// should not be modified while requests are in flight.
+type Client struct {
+	// Backend is the library used to send requests. It can be
+	// changed at any time to switch between WinHTTP, WinINet, or the
+	// Go standard library.
+	Backend Backend
+
+	// CheckRedirect specifies the policy for handling redirects,
+	// same as net/http. If not nil, it is called before following a
+	// redirect with the upcoming Request and the requests made
+	// already, oldest first. If it returns an error, the Client
+	// returns that error, unless it is ErrUseLastResponse, in which
+	// case the redirect Response is returned instead. As it owns the
+	// policy, DefaultMaxRedirects does not apply, so it must stop
+	// redirect loops itself (unless MaxRedirects is set).
+	CheckRedirect func(req *Request, via []*Request) error
+
+	// DisableCompression will prevent the Client from requesting
+	// compressed responses w/ the Accept-Encoding header. By
+	// default, gzip, deflate, and Brotli responses are decoded
+	// transparently for every Backend, see Response.Uncompressed.
+	DisableCompression bool
+
+	// DisableKeepAlives will prevent connections from being reused
+	// between requests, for every Backend.
+	DisableKeepAlives bool
+
+	// DisableRedirects will prevent the Client from following any
+	// redirects, returning the redirect Response instead.
+	DisableRedirects bool
+
+	// IdleConnTimeout is the maximum amount of time an idle
+	// connection is kept for reuse. If 0, DefaultIdleConnTimeout is
+	// used.
+	IdleConnTimeout time.Duration
+
+	// Jar is used to insert cookies into every outbound Request and
+	// is updated with the cookies received in every Response. If
+	// nil, cookies are only sent if explicitly added to the Request.
+	// Any net/http.CookieJar (e.g. net/http/cookiejar) works, as does
+	// the in-memory Jar from NewJar.
+	Jar http.CookieJar
+
+	// MaxConnsPerHost limits the number of connections per scheme,
+	// host, and port, including idle ones. Requests over the limit
+	// block until a connection is available. If 0, there is no limit.
+	MaxConnsPerHost int
+
+	// MaxIdleConns limits the number of idle connections kept for
+	// reuse across all hosts. If 0, DefaultMaxIdleConns is used. If
+	// negative, idle connections are not kept.
+	MaxIdleConns int
+
+	// MaxRedirects is the maximum number of redirects to follow
+	// before failing. If 0, DefaultMaxRedirects is used, unless
+	// CheckRedirect is set.
+	MaxRedirects int
+
+	// Middleware wraps every round trip (each redirect hop and each
+	// retry attempt), see Middleware. The first Middleware is the
+	// outermost.
+	Middleware []Middleware
+
+	// RetryPolicy, if not nil, is used to retry failed requests
+	// (e.g. connection failures or 503 responses).
+	RetryPolicy *RetryPolicy
+
+	Timeout         time.Duration
+	TLSClientConfig struct {
+		InsecureSkipVerify bool
+	}
+}
+
+// NewClient will return a pointer to a new Client instance that uses
+// the provided Backend.
+func NewClient(b Backend) *Client {
+	return &Client{Backend: b}
+}
+
+// Close will release any resources held by the Client's Backend,
+// such as the WinHTTP/WinINet session handle. The Client should not
+// be used afterwards.
+func (c *Client) Close() error {
+	if closer, ok := c.Backend.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// CloseIdleConnections will close any connections that were
+// previously used and are now idle. It does not interrupt any
+// requests in flight.
+func (c *Client) CloseIdleConnections() {
+	type closeIdler interface {
+		CloseIdleConnections()
+	}
+
+	if b, ok := c.Backend.(closeIdler); ok {
+		b.CloseIdleConnections()
+	}
+}
+
+// Do will send the HTTP request and return an HTTP response,
+// following redirects as configured by the Client. The caller must
+// close the Response Body to release the underlying handles. The
+// Request's context, if any, is honored (see DoContext).
+func (c *Client) Do(r *Request) (*Response, error) {
+	var e error
+	var maxRedirects int = c.MaxRedirects
+	var next *Request
+	var redirects []Redirect
+	var res *Response
+	var via []*Request
+
+	defer r.closeBody()
+
+	if (maxRedirects <= 0) && (c.CheckRedirect == nil) {
+		maxRedirects = DefaultMaxRedirects
+	}
+
+	for {
+		res, e = c.sendRetry(r)
+
+		// Bodies of later hops came from GetBody
+		if len(via) > 0 {
+			r.closeBody()
+		}
+
+		if e != nil {
+			return nil, e
+		}
+
+		if c.DisableRedirects || !isRedirect(res.StatusCode) {
+			break
+		}
+
+		if next, e = redirectRequest(r, res); e != nil {
+			res.Body.Close()
+			return nil, e
+		} else if next == nil {
+			// Can't follow (no Location or body can't be replayed)
+			break
+		}
+
+		via = append(via, r)
+
+		if (maxRedirects > 0) && (len(via) > maxRedirects) {
+			next.closeBody()
+			res.Body.Close()
+			e = errors.Newf("stopped after %d redirects", maxRedirects)
+			return nil, e
+		}
+
+		if c.CheckRedirect != nil {
+			if e = c.CheckRedirect(next, via); e != nil {
+				next.closeBody()
+
+				if e == ErrUseLastResponse {
+					break
+				}
+
+				res.Body.Close()
+				return nil, e
+			}
+		}
+
+		// Drain a bit of the body to allow for connection reuse
+		io.CopyN(io.Discard, res.Body, 2<<10)
+		res.Body.Close()
+
+		redirects = append(
+			redirects,
+			Redirect{
+				Location:   next.URL,
+				StatusCode: res.StatusCode,
+				URL:        r.URL,
+			},
+		)
+
+		r = next
+	}
+
+	res.Redirects = redirects
+
+	return res, nil
+}
+
+// DoContext will send the HTTP request using the provided context and
+// return an HTTP response. A canceled or expired context aborts the
+// request, including any reads of the Response Body, and the
+// returned error wraps context.Canceled or context.DeadlineExceeded.
+func (c *Client) DoContext(
+	ctx context.Context,
+	r *Request,
+) (*Response, error) {
+	return c.Do(r.WithContext(ctx))
+}
+
+// Get will make a GET request.
+func (c *Client) Get(url string) (*Response, error) {
+	return c.Do(NewRequest(MethodGet, url))
+}
+
+// Head will make a HEAD request.
+func (c *Client) Head(url string) (*Response, error) {
+	return c.Do(NewRequest(MethodHead, url))
+}
+
+// Post will make a POST request.
+func (c *Client) Post(
+	url string,
+	contentType string,
+	body []byte,
+) (*Response, error) {
+	var r *Request = NewRequest(MethodPost, url, body)
+
+	if contentType != "" {
+		r.Headers.Set("Content-Type", contentType)
+	}
+
+	return c.Do(r)
+}
+
+// PostForm will make a POST request w/ the provided data
+// URL-encoded as the body.
+func (c *Client) PostForm(url string, data url.Values) (*Response, error) {
+	return c.Post(
+		url,
+		"application/x-www-form-urlencoded",
+		[]byte(data.Encode()),
+	)
+}
+
+// PostMultipart will make a POST request w/ the Multipart body, see
+// NewMultipartRequest.
+func (c *Client) PostMultipart(url string, m *Multipart) (*Response, error) {
+	return c.Do(NewMultipartRequest(MethodPost, url, m))
+}
+
+// send will make a single round trip using the Middleware chain and
+// the Backend. Cookies from the Jar, if any, are added to the Request
+// and received cookies are stored in the Jar.
+func (c *Client) send(r *Request) (*Response, error) {
+	var cookies []*Cookie
+	var e error
+	var res *Response
+	var stdCookies []*http.Cookie
+	var uri *url.URL
+
+	if e = r.Context().Err(); e != nil {
+		return nil, errors.Newf("request aborted: %w", e)
+	}
+
+	if c.Jar != nil {
+		if uri, e = url.Parse(r.URL); e != nil {
+			return nil, errors.Newf("failed to parse url %s: %w", r.URL, e)
+		}
+
+		r = withJarCookies(r, c.Jar.Cookies(uri))
+	}
+
+	if res, e = c.roundTrip()(r); e != nil {
+		return nil, e
+	} else if res == nil {
+		return nil, errors.New("no response returned")
+	}
+
+	// Only received cookies, the Jar has the merged view
+	cookies = res.cookies
+	res.cookies = nil
+
+	if (c.Jar != nil) && (len(cookies) > 0) {
+		for _, cookie := range cookies {
+			stdCookies = append(stdCookies, toStdCookie(cookie))
+		}
+
+		c.Jar.SetCookies(uri, stdCookies)
+	}
+
+	for _, cookie := range cookies {
+		res.AddCookie(cookie)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,387 @@
+package core
+
+import (
+	"context"
+	goerrors "errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mjwhitta/win/errors"
+)
+
+// concurrency is the number of goroutines sharing a Client.
+const concurrency int = 32
+
+// closeBackend is a Backend that records whether it was closed.
+type closeBackend struct {
+	closed bool
+}
+
+func (b *closeBackend) Close() error {
+	b.closed = true
+	return nil
+}
+
+func (b *closeBackend) Send(c *Client, r *Request) (*Response, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestClientClose(t *testing.T) {
+	var b *closeBackend = &closeBackend{}
+	var e error
+	var std Backend
+
+	if e = NewClient(b).Close(); e != nil {
+		t.Fatal(e)
+	} else if !b.closed {
+		t.Error("Backend was not closed")
+	}
+
+	// The Go standard library Backend holds no handles
+	if std, e = NewStd("test", ""); e != nil {
+		t.Fatal(e)
+	}
+
+	if e = NewClient(std).Close(); e != nil {
+		t.Fatal(e)
+	}
+
+	if n := LiveHandles(); n != 0 {
+		t.Errorf("got %d live handles, expected 0", n)
+	}
+}
+
+// TestConcurrentClose checks that closing a Client w/ requests in
+// flight is safe. Requests may fail, but must not race or panic.
+func TestConcurrentClose(t *testing.T) {
+	var b Backend
+	var c *Client
+	var e error
+	var f *Fake = NewFake()
+	var srv *httptest.Server = httptest.NewServer(testHandler())
+	var wg sync.WaitGroup
+
+	defer srv.Close()
+
+	f.HandleRequest(
+		"/reset",
+		func(r *Request) (*Response, error) {
+			return nil, syscall.ECONNRESET
+		},
+	)
+
+	if b, e = NewStd("test", ""); e != nil {
+		t.Fatal(e)
+	}
+
+	for _, backend := range []Backend{b, f} {
+		c, _ = testClient(backend)
+
+		for i := 0; i < concurrency; i++ {
+			wg.Add(1)
+
+			go func(i int) {
+				var e error
+				var res *Response
+				var url string = srv.URL + "/redirect?n=" + strconv.Itoa(i)
+
+				defer wg.Done()
+
+				if backend == f {
+					url = "http://example.com/reset"
+				}
+
+				for j := 0; j < 5; j++ {
+					if res, e = c.Get(url); e == nil {
+						io.Copy(io.Discard, res.Body)
+						res.Body.Close()
+					}
+				}
+			}(i)
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			c.CloseIdleConnections()
+			c.Close()
+		}()
+
+		wg.Wait()
+	}
+}
+
+func TestConcurrentDo(t *testing.T) {
+	var backends map[string]func(t *testing.T) (Backend, string)
+
+	backends = map[string]func(t *testing.T) (Backend, string){
+		"Fake": func(t *testing.T) (Backend, string) {
+			var f *Fake = NewFake()
+
+			f.Handle("/", testHandler())
+
+			return f, "http://example.com"
+		},
+		"Std": func(t *testing.T) (Backend, string) {
+			var b Backend
+			var e error
+			var srv *httptest.Server = httptest.NewServer(testHandler())
+
+			t.Cleanup(srv.Close)
+
+			if b, e = NewStd("test", ""); e != nil {
+				t.Fatal(e)
+			}
+
+			return b, srv.URL
+		},
+	}
+
+	for name, newBackend := range backends {
+		t.Run(
+			name,
+			func(t *testing.T) {
+				var b Backend
+				var base string
+				var c *Client
+				var e error
+				var har *HAR
+				var rec *HARRecorder
+
+				b, base = newBackend(t)
+				c, rec = testClient(b)
+				defer c.Close()
+
+				testConcurrentDo(t, c, base)
+
+				// Every cookie ended up in the shared Jar
+				if n := len(c.Jar.(*Jar).Export()); n != concurrency {
+					t.Errorf("got %d cookies, expected %d", n, concurrency)
+				}
+
+				// 4 round trips per goroutine, plus retries
+				if har, e = rec.HAR(); e != nil {
+					t.Fatal(e)
+				} else if len(har.Log.Entries) < 4*concurrency {
+					t.Errorf(
+						"got %d HAR entries, expected at least %d",
+						len(har.Log.Entries),
+						4*concurrency,
+					)
+				}
+			},
+		)
+	}
+}
+
+func TestDoContext(t *testing.T) {
+	var b Backend
+	var c *Client
+	var cancel context.CancelFunc
+	var ctx context.Context
+	var done chan struct{} = make(chan struct{})
+	var e error
+	var res *Response
+	var srv *httptest.Server
+
+	srv = httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/body" {
+					w.Write([]byte("partial"))
+					w.(http.Flusher).Flush()
+				}
+
+				select {
+				case <-done:
+				case <-r.Context().Done():
+				}
+			},
+		),
+	)
+	defer srv.Close()
+	defer close(done)
+
+	if b, e = NewStd("test", ""); e != nil {
+		t.Fatal(e)
+	}
+
+	c = NewClient(b)
+
+	// Already canceled
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+
+	_, e = c.DoContext(ctx, NewRequest(MethodGet, srv.URL))
+	if !goerrors.Is(e, context.Canceled) {
+		t.Errorf("got %v, expected context.Canceled", e)
+	}
+
+	// Deadline while waiting for the headers
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, e = c.Do(NewRequestWithContext(ctx, MethodGet, srv.URL))
+	if !goerrors.Is(e, context.DeadlineExceeded) {
+		t.Errorf("got %v, expected context.DeadlineExceeded", e)
+	}
+
+	// Canceled while reading the body
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	res, e = c.DoContext(ctx, NewRequest(MethodGet, srv.URL+"/body"))
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer res.Body.Close()
+
+	cancel()
+
+	if _, e = io.ReadAll(res.Body); !goerrors.Is(e, context.Canceled) {
+		t.Errorf("got %v, expected context.Canceled", e)
+	}
+}
+
+// TestWatchContext checks the helper used by the Windows backends to
+// close handles when a context is done.
+func TestWatchContext(t *testing.T) {
+	var aborted atomic.Bool
+	var cancel context.CancelFunc
+	var ctx context.Context
+	var stop func() bool
+
+	ctx, cancel = context.WithCancel(context.Background())
+	stop = watchContext(ctx, func() { aborted.Store(true) })
+
+	if stop() || aborted.Load() {
+		t.Error("aborted w/o cancel")
+	}
+
+	// Calling stop again is safe
+	if stop() {
+		t.Error("aborted w/o cancel")
+	}
+
+	stop = watchContext(ctx, func() { aborted.Store(true) })
+	cancel()
+
+	// Abort is called asynchronously
+	for i := 0; (i < 100) && !aborted.Load(); i++ {
+		time.Sleep(time.Millisecond)
+	}
+
+	if !stop() || !aborted.Load() {
+		t.Error("not aborted after cancel")
+	}
+
+	// Contexts that are never done aren't watched
+	if watchContext(context.Background(), nil)() {
+		t.Error("aborted w/o context")
+	}
+}
+
+// testClient will return a Client w/ everything that is shared
+// between goroutines: a Jar, a RetryPolicy, and a HARRecorder.
+func testClient(b Backend) (*Client, *HARRecorder) {
+	var c *Client = NewClient(b)
+	var rec *HARRecorder = NewHARRecorder()
+
+	c.Jar = NewJar()
+	c.Middleware = []Middleware{rec.Middleware}
+	c.RetryPolicy = &RetryPolicy{
+		MaxBackoff: time.Millisecond,
+		MaxRetries: 10,
+		MinBackoff: time.Millisecond,
+	}
+
+	return c, rec
+}
+
+// testConcurrentDo will send requests from many goroutines using the
+// same Client and check each Response.
+func testConcurrentDo(t *testing.T, c *Client, base string) {
+	var wg sync.WaitGroup
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			var b []byte
+			var e error
+			var n string = strconv.Itoa(i)
+			var res *Response
+
+			defer wg.Done()
+
+			for _, path := range []string{"/echo", "/redirect", "/flaky"} {
+				res, e = c.Get(base + path + "?n=" + n)
+				if e != nil {
+					t.Error(e)
+					return
+				}
+
+				b, e = io.ReadAll(res.Body)
+				res.Body.Close()
+
+				if e != nil {
+					t.Error(e)
+				} else if string(b) != n {
+					t.Errorf("%s: got %q, expected %q", path, b, n)
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+// testHandler is used by both the Std and Fake backends. It sets a
+// cookie, redirects, and fails every other request to /flaky.
+func testHandler() http.Handler {
+	var flaky atomic.Int64
+	var mux *http.ServeMux = http.NewServeMux()
+
+	mux.HandleFunc(
+		"/echo",
+		func(w http.ResponseWriter, r *http.Request) {
+			var n string = r.URL.Query().Get("n")
+
+			http.SetCookie(w, &http.Cookie{Name: "c" + n, Value: n})
+			w.Write([]byte(n))
+		},
+	)
+	mux.HandleFunc(
+		"/flaky",
+		func(w http.ResponseWriter, r *http.Request) {
+			if flaky.Add(1)%2 == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			w.Write([]byte(r.URL.Query().Get("n")))
+		},
+	)
+	mux.HandleFunc(
+		"/redirect",
+		func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(
+				w,
+				r,
+				"/echo?"+r.URL.RawQuery,
+				http.StatusFound,
+			)
+		},
+	)
+
+	return mux
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strings"
+
+	"github.com/mjwhitta/win/brotli"
+	"github.com/mjwhitta/win/errors"
+)
+
+// acceptEncoding is the Accept-Encoding header sent when compression
+// is enabled.
+const acceptEncoding string = "gzip, deflate, br"
+
+// decompressReader will lazily decode a compressed response body, so
+// the Response is returned before any of the body is read.
+type decompressReader struct {
+	body     io.ReadCloser
+	encoding string
+	err      error
+	r        io.Reader
+}
+
+// decompress will request compressed responses, unless the Request
+// already has an Accept-Encoding or Range header, and transparently
+// decode them, same as net/http (plus Brotli). It is the innermost
+// Middleware of every Client w/o DisableCompression, so the behavior
+// is the same for every Backend and Windows version, and
+// WinHTTP/WinINet decoding is left disabled.
+func decompress(next RoundTripFunc) RoundTripFunc {
+	return func(r *Request) (*Response, error) {
+		var e error
+		var encoding string
+		var res *Response
+
+		if (r.Method == MethodHead) ||
+			(r.Headers.Get("Accept-Encoding") != "") ||
+			(r.Headers.Get("Range") != "") {
+			return next(r)
+		}
+
+		r = r.Clone(r.Context())
+		r.Headers.Set("Accept-Encoding", acceptEncoding)
+
+		if res, e = next(r); e != nil {
+			return nil, e
+		}
+
+		if len(res.Header.Values("Content-Encoding")) != 1 {
+			return res, nil
+		}
+
+		encoding = strings.ToLower(
+			strings.TrimSpace(res.Header.Get("Content-Encoding")),
+		)
+
+		switch encoding {
+		case "br", "deflate", "gzip", "x-gzip":
+		default:
+			return res, nil
+		}
+
+		res.Body = &decompressReader{body: res.Body, encoding: encoding}
+		res.ContentLength = -1
+		res.Header.Del("Content-Encoding")
+		res.Header.Del("Content-Length")
+		res.Uncompressed = true
+
+		return res, nil
+	}
+}
+
+// isZlibHeader will return true if b starts with a valid zlib header
+// (RFC 1950), using the deflate method.
+func isZlibHeader(b []byte) bool {
+	return ((b[0] & 0x0f) == 8) &&
+		(((uint16(b[0])<<8)|uint16(b[1]))%31 == 0)
+}
+
+// newDecoder will return an io.Reader that decodes the provided
+// Content-Encoding.
+func newDecoder(encoding string, r io.Reader) (io.Reader, error) {
+	var b []byte
+	var br *bufio.Reader
+	var e error
+
+	switch encoding {
+	case "br":
+		return brotli.NewReader(r), nil
+	case "deflate":
+		// Most servers send zlib as specified, but some send raw
+		// deflate
+		br = bufio.NewReader(r)
+
+		if b, e = br.Peek(2); (e == nil) && isZlibHeader(b) {
+			return zlib.NewReader(br)
+		}
+
+		return flate.NewReader(br), nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	}
+
+	return nil, errors.Newf("unsupported encoding %s", encoding)
+}
+
+// Close will close the underlying body.
+func (d *decompressReader) Close() error {
+	if c, ok := d.r.(io.Closer); ok {
+		c.Close()
+	}
+
+	return d.body.Close()
+}
+
+// Read will read decoded bytes from the underlying body.
+func (d *decompressReader) Read(b []byte) (int, error) {
+	var r io.Reader
+
+	if (d.r == nil) && (d.err == nil) {
+		// Avoid storing a typed nil on error
+		if r, d.err = newDecoder(d.encoding, d.body); d.err == nil {
+			d.r = r
+		}
+	}
+
+	if d.err != nil {
+		return 0, d.err
+	}
+
+	return d.r.Read(b)
+}
@@ -0,0 +1,314 @@
+package core
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// brotliStored will return s (up to 64 KiB) as a Brotli stream w/ a
+// 64 KiB window, one uncompressed meta-block, and an empty last
+// meta-block.
+func brotliStored(s string) []byte {
+	// WBITS=0, ISLAST=0, MNIBBLES=4, MLEN-1, ISUNCOMPRESSED=1
+	var hdr uint32 = uint32(len(s)-1)<<4 | 1<<20
+
+	return append(
+		append([]byte{byte(hdr), byte(hdr >> 8), byte(hdr >> 16)}, s...),
+		0x03, // ISLAST=1, ISLASTEMPTY=1
+	)
+}
+
+// compressed will return s encoded w/ the provided Content-Encoding.
+// Brotli streams are built w/ uncompressed meta-blocks.
+func compressed(encoding string, s string) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+
+	switch encoding {
+	case "br":
+		return brotliStored(s)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "raw":
+		w, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	default:
+		return []byte(s)
+	}
+
+	w.Write([]byte(s))
+	w.Close()
+
+	return buf.Bytes()
+}
+
+// newCompressServer will return a server that responds to /<enc>
+// w/ "hello" encoded w/ <enc>, and echoes the Accept-Encoding header
+// in X-Accept-Encoding.
+func newCompressServer() *httptest.Server {
+	return httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				var enc string = r.URL.Path[1:]
+
+				w.Header().Set(
+					"X-Accept-Encoding",
+					r.Header.Get("Accept-Encoding"),
+				)
+
+				switch enc {
+				case "corrupt":
+					w.Header().Set("Content-Encoding", "gzip")
+					w.Write([]byte("not gzip"))
+				case "double":
+					w.Header().Add("Content-Encoding", "gzip")
+					w.Header().Add("Content-Encoding", "gzip")
+					w.Write(compressed("gzip", "hello"))
+				case "empty":
+					w.Header().Set("Content-Encoding", "gzip")
+					w.WriteHeader(http.StatusNoContent)
+				case "raw":
+					w.Header().Set("Content-Encoding", "deflate")
+					w.Write(compressed(enc, "hello"))
+				case "unknown":
+					w.Header().Set("Content-Encoding", "zstd")
+					w.Write([]byte("hello"))
+				default:
+					if enc != "identity" {
+						w.Header().Set("Content-Encoding", enc)
+					}
+
+					w.Write(compressed(enc, "hello"))
+				}
+			},
+		),
+	)
+}
+
+func TestDecompress(t *testing.T) {
+	var b Backend
+	var c *Client
+	var e error
+	var out []byte
+	var res *Response
+	var srv *httptest.Server = newCompressServer()
+
+	defer srv.Close()
+
+	if b, e = NewStd("test", ""); e != nil {
+		t.Fatal(e)
+	}
+
+	c = NewClient(b)
+
+	for _, test := range []struct {
+		body         string
+		path         string
+		uncompressed bool
+	}{
+		{"hello", "/br", true},
+		{"hello", "/gzip", true},
+		{"hello", "/deflate", true},
+		{"hello", "/raw", true},
+		{"hello", "/identity", false},
+		{"", "/empty", true},
+		{"hello", "/unknown", false},
+		{string(compressed("gzip", "hello")), "/double", false},
+	} {
+		if res, e = c.Get(srv.URL + test.path); e != nil {
+			t.Fatal(e)
+		}
+
+		out, e = io.ReadAll(res.Body)
+		res.Body.Close()
+
+		if e != nil {
+			t.Errorf("%s: %s", test.path, e)
+			continue
+		}
+
+		if string(out) != test.body {
+			t.Errorf("%s: got %q, expected %q", test.path, out, test.body)
+		}
+
+		if res.Uncompressed != test.uncompressed {
+			t.Errorf(
+				"%s: got Uncompressed %v, expected %v",
+				test.path,
+				res.Uncompressed,
+				test.uncompressed,
+			)
+		}
+
+		if v := res.Header.Get("X-Accept-Encoding"); v != acceptEncoding {
+			t.Errorf("%s: got Accept-Encoding %q", test.path, v)
+		}
+
+		if !res.Uncompressed {
+			continue
+		}
+
+		// Same as net/http
+		if res.ContentLength != -1 {
+			t.Errorf(
+				"%s: got ContentLength %d, expected -1",
+				test.path,
+				res.ContentLength,
+			)
+		}
+
+		for _, k := range []string{"Content-Encoding", "Content-Length"} {
+			if v := res.Header.Get(k); v != "" {
+				t.Errorf("%s: got %s %q, expected none", test.path, k, v)
+			}
+		}
+	}
+
+	// Errors are returned when reading the body
+	if res, e = c.Get(srv.URL + "/corrupt"); e != nil {
+		t.Fatal(e)
+	}
+
+	_, e = io.ReadAll(res.Body)
+	res.Body.Close()
+
+	if e == nil {
+		t.Error("expected error for corrupt gzip")
+	}
+}
+
+func TestDecompressSkipped(t *testing.T) {
+	var b Backend
+	var c *Client
+	var e error
+	var out []byte
+	var req *Request
+	var res *Response
+	var srv *httptest.Server = newCompressServer()
+
+	defer srv.Close()
+
+	if b, e = NewStd("test", ""); e != nil {
+		t.Fatal(e)
+	}
+
+	c = NewClient(b)
+
+	// HEAD, caller's Accept-Encoding, and Range are left alone
+	for _, test := range []struct {
+		accept string
+		hdr    string
+		method string
+		val    string
+	}{
+		{"", "", MethodHead, ""},
+		{"gzip", "Accept-Encoding", MethodGet, "gzip"},
+		{"", "Range", MethodGet, "bytes=0-"},
+	} {
+		req = NewRequest(test.method, srv.URL+"/gzip")
+
+		if test.hdr != "" {
+			req.Headers.Set(test.hdr, test.val)
+		}
+
+		if res, e = c.Do(req); e != nil {
+			t.Fatal(e)
+		}
+
+		out, _ = io.ReadAll(res.Body)
+		res.Body.Close()
+
+		if res.Uncompressed {
+			t.Errorf("%s %s: decompressed", test.method, test.hdr)
+		}
+
+		if v := res.Header.Get("X-Accept-Encoding"); v != test.accept {
+			t.Errorf("%s: got Accept-Encoding %q", test.hdr, v)
+		}
+
+		if (test.method == MethodGet) &&
+			!bytes.Equal(out, compressed("gzip", "hello")) {
+			t.Errorf("%s: got %q, expected gzip", test.hdr, out)
+		}
+
+		// Headers are kept as received
+		if res.Header.Get("Content-Encoding") != "gzip" {
+			t.Errorf("%s: Content-Encoding was removed", test.hdr)
+		}
+	}
+
+	// DisableCompression
+	c.DisableCompression = true
+
+	if res, e = c.Get(srv.URL + "/identity"); e != nil {
+		t.Fatal(e)
+	}
+	res.Body.Close()
+
+	if v := res.Header.Get("X-Accept-Encoding"); v != "" {
+		t.Errorf("got Accept-Encoding %q, expected none", v)
+	}
+}
+
+// TestDecompressHAR checks that the HAR records the Accept-Encoding
+// header that was sent and doesn't report a decoded body size.
+func TestDecompressHAR(t *testing.T) {
+	var b Backend
+	var c *Client
+	var e error
+	var entry HAREntry
+	var har *HAR
+	var rec *HARRecorder = NewHARRecorder()
+	var res *Response
+	var srv *httptest.Server = newCompressServer()
+
+	defer srv.Close()
+
+	if b, e = NewStd("test", ""); e != nil {
+		t.Fatal(e)
+	}
+
+	c = NewClient(b)
+	c.Middleware = []Middleware{rec.Middleware}
+
+	for _, path := range []string{"/gzip", "/identity"} {
+		if res, e = c.Get(srv.URL + path); e != nil {
+			t.Fatal(e)
+		}
+
+		io.ReadAll(res.Body)
+		res.Body.Close()
+	}
+
+	if har, e = rec.HAR(); e != nil {
+		t.Fatal(e)
+	}
+
+	for i, expected := range []int64{-1, 5} {
+		entry = har.Log.Entries[i]
+
+		v := harValue(entry.Request.Headers, "Accept-Encoding")
+		if v != acceptEncoding {
+			t.Errorf("got Accept-Encoding %q", v)
+		}
+
+		if entry.Response.BodySize != expected {
+			t.Errorf(
+				"got bodySize %d, expected %d",
+				entry.Response.BodySize,
+				expected,
+			)
+		}
+
+		if entry.Response.Content.Text != "hello" {
+			t.Errorf("got text %q", entry.Response.Content.Text)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package core
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cookie represents an HTTP cookie sent in the Cookie header of an
+// HTTP Request or received in the Set-Cookie header of an HTTP
+// Response.
+type Cookie struct {
+	// Domain is the lowercase domain, w/o a leading dot. If empty,
+	// the cookie is host-only.
+	Domain string
+
+	// Expires is the zero time if the Expires attribute was missing
+	// or invalid.
+	Expires time.Time
+
+	// HostOnly means the cookie is only sent to Domain and not its
+	// subdomains. It is only meaningful for stored cookies (e.g. from
+	// a Jar or cookies.txt), where Domain is always set.
+	HostOnly bool
+
+	HttpOnly bool
+
+	// MaxAge=0 means no Max-Age attribute was specified, MaxAge<0
+	// means delete the cookie now (i.e. "Max-Age=0"), and MaxAge>0
+	// is the lifetime in seconds.
+	MaxAge int
+
+	Name string
+
+	// Path is empty if the Path attribute was missing or invalid.
+	Path string
+
+	// Raw is the Set-Cookie header the Cookie was parsed from, if
+	// any.
+	Raw string
+
+	SameSite SameSite
+	Secure   bool
+	Value    string
+}
+
+// SameSite allows a server to define a cookie attribute making it
+// impossible for the browser to send this cookie along with
+// cross-site requests.
+type SameSite int
+
+// Valid SameSite values, same as net/http.
+const (
+	SameSiteDefaultMode SameSite = iota + 1
+	SameSiteLaxMode
+	SameSiteStrictMode
+	SameSiteNoneMode
+)
+
+var cookieMonths = map[string]time.Month{
+	"jan": time.January,
+	"feb": time.February,
+	"mar": time.March,
+	"apr": time.April,
+	"may": time.May,
+	"jun": time.June,
+	"jul": time.July,
+	"aug": time.August,
+	"sep": time.September,
+	"oct": time.October,
+	"nov": time.November,
+	"dec": time.December,
+}
+
+// isCookieDelim will return true for the delimiters defined in RFC
+// 6265 section 5.1.1.
+func isCookieDelim(r rune) bool {
+	switch {
+	case r == 0x09:
+	case (r >= 0x20) && (r <= 0x2f):
+	case (r >= 0x3b) && (r <= 0x40):
+	case (r >= 0x5b) && (r <= 0x60):
+	case (r >= 0x7b) && (r <= 0x7e):
+	default:
+		return false
+	}
+
+	return true
+}
+
+func leadingDigits(token string, lo int, hi int) (int, string, bool) {
+	var i int
+	var n int
+
+	for (i < len(token)) && (token[i] >= '0') && (token[i] <= '9') {
+		i++
+	}
+
+	if (i < lo) || (i > hi) {
+		return 0, "", false
+	}
+
+	n, _ = strconv.Atoi(token[:i])
+
+	return n, token[i:], true
+}
+
+// parseCookieDate will parse a cookie date using the algorithm from
+// RFC 6265 section 5.1.1, which is far more lenient than any single
+// time layout.
+func parseCookieDate(str string) (time.Time, bool) {
+	var day int
+	var foundDay bool
+	var foundMonth bool
+	var foundTime bool
+	var foundYear bool
+	var hour int
+	var minute int
+	var month time.Month
+	var ok bool
+	var rest string
+	var sec int
+	var year int
+
+	for _, token := range strings.FieldsFunc(str, isCookieDelim) {
+		if !foundTime {
+			if hour, minute, sec, ok = parseCookieTime(token); ok {
+				foundTime = true
+				continue
+			}
+		}
+
+		if !foundDay {
+			if day, rest, ok = leadingDigits(token, 1, 2); ok {
+				if rest == "" {
+					foundDay = true
+					continue
+				}
+			}
+		}
+
+		if !foundMonth && (len(token) >= 3) {
+			month, ok = cookieMonths[strings.ToLower(token[:3])]
+			if ok {
+				foundMonth = true
+				continue
+			}
+		}
+
+		if !foundYear {
+			if year, rest, ok = leadingDigits(token, 2, 4); ok {
+				if rest == "" {
+					foundYear = true
+					continue
+				}
+			}
+		}
+	}
+
+	if !foundDay || !foundMonth || !foundTime || !foundYear {
+		return time.Time{}, false
+	}
+
+	switch {
+	case (year >= 70) && (year <= 99):
+		year += 1900
+	case (year >= 0) && (year <= 69):
+		year += 2000
+	}
+
+	switch {
+	case (day < 1) || (day > 31):
+		return time.Time{}, false
+	case year < 1601:
+		return time.Time{}, false
+	case (hour > 23) || (minute > 59) || (sec > 59):
+		return time.Time{}, false
+	}
+
+	return time.Date(year, month, day, hour, minute, sec, 0, time.UTC), true
+}
+
+func parseCookieTime(token string) (int, int, int, bool) {
+	var ok bool
+	var vals [3]int
+
+	for i := range vals {
+		if vals[i], token, ok = leadingDigits(token, 1, 2); !ok {
+			return 0, 0, 0, false
+		}
+
+		if i < 2 {
+			if !strings.HasPrefix(token, ":") {
+				return 0, 0, 0, false
+			}
+
+			token = token[1:]
+		}
+	}
+
+	return vals[0], vals[1], vals[2], true
+}
+
+// parseSetCookie will parse a Set-Cookie header as described in RFC
+// 6265 section 5.2. Malformed headers return nil rather than
+// panicking. Unrecognized or invalid attributes are ignored.
+func parseSetCookie(raw string) *Cookie {
+	var attr string
+	var c *Cookie
+	var e error
+	var n int
+	var parts []string = strings.Split(raw, ";")
+	var tmp []string
+	var val string
+
+	// Name-value pair is required
+	if tmp = strings.SplitN(parts[0], "=", 2); len(tmp) != 2 {
+		return nil
+	}
+
+	c = &Cookie{
+		Name:  strings.TrimSpace(tmp[0]),
+		Raw:   raw,
+		Value: strings.TrimSpace(tmp[1]),
+	}
+
+	if c.Name == "" {
+		return nil
+	}
+
+	// Same as net/http, strip a matching pair of surrounding quotes
+	if (len(c.Value) > 1) &&
+		(c.Value[0] == '"') &&
+		(c.Value[len(c.Value)-1] == '"') {
+		c.Value = c.Value[1 : len(c.Value)-1]
+	}
+
+	for _, part := range parts[1:] {
+		tmp = strings.SplitN(part, "=", 2)
+		attr = strings.ToLower(strings.TrimSpace(tmp[0]))
+		val = ""
+
+		if len(tmp) == 2 {
+			val = strings.TrimSpace(tmp[1])
+		}
+
+		switch attr {
+		case "domain":
+			val = strings.TrimPrefix(val, ".")
+			if val != "" {
+				c.Domain = strings.ToLower(val)
+			}
+		case "expires":
+			if t, ok := parseCookieDate(val); ok {
+				c.Expires = t
+			}
+		case "httponly":
+			c.HttpOnly = true
+		case "max-age":
+			// Only DIGIT or a leading "-" (Atoi would accept "+")
+			switch {
+			case val == "":
+				continue
+			case val[0] == '-':
+			case (val[0] < '0') || (val[0] > '9'):
+				continue
+			}
+
+			if n, e = strconv.Atoi(val); e != nil {
+				continue
+			}
+
+			if n <= 0 {
+				n = -1
+			}
+
+			c.MaxAge = n
+		case "path":
+			if strings.HasPrefix(val, "/") {
+				c.Path = val
+			}
+		case "samesite":
+			switch strings.ToLower(val) {
+			case "lax":
+				c.SameSite = SameSiteLaxMode
+			case "none":
+				c.SameSite = SameSiteNoneMode
+			case "strict":
+				c.SameSite = SameSiteStrictMode
+			default:
+				c.SameSite = SameSiteDefaultMode
+			}
+		case "secure":
+			c.Secure = true
+		}
+	}
+
+	return c
+}
+
+// String will return the serialization of the Cookie for use in a
+// Set-Cookie header. If only Name and Value are set, it is suitable
+// for a Cookie header as well.
+func (c *Cookie) String() string {
+	var sb strings.Builder
+
+	sb.WriteString(c.Name + "=" + c.Value)
+
+	if c.Path != "" {
+		sb.WriteString("; Path=" + c.Path)
+	}
+
+	if c.Domain != "" {
+		sb.WriteString("; Domain=" + c.Domain)
+	}
+
+	if !c.Expires.IsZero() {
+		sb.WriteString(
+			"; Expires=" +
+				c.Expires.UTC().Format(
+					"Mon, 02 Jan 2006 15:04:05 GMT",
+				),
+		)
+	}
+
+	switch {
+	case c.MaxAge > 0:
+		sb.WriteString("; Max-Age=" + strconv.Itoa(c.MaxAge))
+	case c.MaxAge < 0:
+		sb.WriteString("; Max-Age=0")
+	}
+
+	if c.HttpOnly {
+		sb.WriteString("; HttpOnly")
+	}
+
+	if c.Secure {
+		sb.WriteString("; Secure")
+	}
+
+	switch c.SameSite {
+	case SameSiteLaxMode:
+		sb.WriteString("; SameSite=Lax")
+	case SameSiteNoneMode:
+		sb.WriteString("; SameSite=None")
+	case SameSiteStrictMode:
+		sb.WriteString("; SameSite=Strict")
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,150 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCookieDate(t *testing.T) {
+	var expected time.Time = time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)
+	var ok bool
+	var tm time.Time
+
+	for _, test := range []struct {
+		in string
+		ok bool
+	}{
+		{"Wed, 21 Oct 2015 07:28:00 GMT", true},
+		{"Wednesday, 21-Oct-15 07:28:00 GMT", true},
+		{"Wed Oct 21 07:28:00 2015", true},
+		{"21 october 2015 7:28:0", true},
+		{"Wed, 21 Oct 2015", false},
+		{"Wed, 32 Oct 2015 07:28:00 GMT", false},
+		{"Wed, 21 Foo 2015 07:28:00 GMT", false},
+		{"Wed, 21 Oct 2015 24:28:00 GMT", false},
+		{"Wed, 21 Oct 1600 07:28:00 GMT", false},
+		{"", false},
+	} {
+		if tm, ok = parseCookieDate(test.in); ok != test.ok {
+			t.Errorf("%q: got %v, expected %v", test.in, ok, test.ok)
+		} else if ok && !tm.Equal(expected) {
+			t.Errorf("%q: got %s, expected %s", test.in, tm, expected)
+		}
+	}
+
+	// Two digit years
+	if tm, _ = parseCookieDate("1 Jan 69 00:00:00"); tm.Year() != 2069 {
+		t.Errorf("got year %d, expected 2069", tm.Year())
+	}
+
+	if tm, _ = parseCookieDate("1 Jan 70 00:00:00"); tm.Year() != 1970 {
+		t.Errorf("got year %d, expected 1970", tm.Year())
+	}
+}
+
+func TestParseSetCookie(t *testing.T) {
+	var c *Cookie
+
+	for _, test := range []struct {
+		expected *Cookie
+		raw      string
+	}{
+		// Malformed name-value pairs
+		{nil, ""},
+		{nil, "novalue"},
+		{nil, "=value"},
+		{nil, " ; Path=/"},
+
+		// Quotes
+		{&Cookie{Name: "a", Value: "b"}, `a="b"`},
+		{&Cookie{Name: "a", Value: `"b`}, `a="b`},
+		{&Cookie{Name: "a", Value: `b"`}, `a=b"`},
+		{&Cookie{Name: "a", Value: `"`}, `a="`},
+		{&Cookie{Name: "a", Value: ""}, `a=""`},
+
+		// Max-Age
+		{&Cookie{Name: "a", Value: "b", MaxAge: 5}, "a=b; Max-Age=5"},
+		{&Cookie{Name: "a", Value: "b", MaxAge: -1}, "a=b; max-age=0"},
+		{&Cookie{Name: "a", Value: "b", MaxAge: -1}, "a=b; Max-Age=-5"},
+		{&Cookie{Name: "a", Value: "b"}, "a=b; Max-Age=+5"},
+		{&Cookie{Name: "a", Value: "b"}, "a=b; Max-Age= "},
+		{&Cookie{Name: "a", Value: "b"}, "a=b; Max-Age=-"},
+		{&Cookie{Name: "a", Value: "b"}, "a=b; Max-Age=5s"},
+		{&Cookie{Name: "a", Value: "b"}, "a=b; Max-Age"},
+
+		// Expires
+		{
+			&Cookie{
+				Expires: time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC),
+				Name:    "a",
+				Value:   "b",
+			},
+			"a=b; Expires=Wed, 21 Oct 2015 07:28:00 GMT",
+		},
+		{&Cookie{Name: "a", Value: "b"}, "a=b; Expires=never"},
+
+		// Other attributes
+		{
+			&Cookie{
+				Domain:   "example.com",
+				HttpOnly: true,
+				Name:     "a",
+				Path:     "/x",
+				SameSite: SameSiteStrictMode,
+				Secure:   true,
+				Value:    "b",
+			},
+			" a = b ; DOMAIN=.Example.COM; path=/x; secure; HttpOnly; " +
+				"SameSite=strict; Unknown=1",
+		},
+		{&Cookie{Name: "a", Value: "b"}, "a=b; Path=x; Domain=."},
+		{
+			&Cookie{Name: "a", SameSite: SameSiteDefaultMode, Value: "b"},
+			"a=b; SameSite=bogus",
+		},
+		{&Cookie{Name: "a", Value: "b=c"}, "a=b=c;;"},
+	} {
+		if c = parseSetCookie(test.raw); test.expected == nil {
+			if c != nil {
+				t.Errorf("%q: got %+v, expected nil", test.raw, c)
+			}
+
+			continue
+		} else if c == nil {
+			t.Errorf("%q: got nil", test.raw)
+			continue
+		}
+
+		test.expected.Raw = test.raw
+
+		if *c != *test.expected {
+			t.Errorf("%q: got %+v, expected %+v", test.raw, c, test.expected)
+		}
+	}
+}
+
+func TestCookieString(t *testing.T) {
+	var c *Cookie = &Cookie{
+		Domain:   "example.com",
+		Expires:  time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC),
+		HttpOnly: true,
+		MaxAge:   -1,
+		Name:     "a",
+		Path:     "/",
+		SameSite: SameSiteLaxMode,
+		Secure:   true,
+		Value:    "b",
+	}
+	var expected string = "a=b; Path=/; Domain=example.com; " +
+		"Expires=Wed, 21 Oct 2015 07:28:00 GMT; Max-Age=0; HttpOnly; " +
+		"Secure; SameSite=Lax"
+
+	if c.String() != expected {
+		t.Errorf("got %q, expected %q", c.String(), expected)
+	}
+
+	// Round trip
+	if c2 := parseSetCookie(c.String()); c2.String() != expected {
+		t.Errorf("got %q, expected %q", c2.String(), expected)
+	}
+}
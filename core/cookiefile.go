@@ -0,0 +1,272 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mjwhitta/win/errors"
+)
+
+// jsonCookie is the JSON representation of a Cookie. It uses the
+// same fields as common browser cookie exporters.
+type jsonCookie struct {
+	Domain         string  `json:"domain"`
+	ExpirationDate float64 `json:"expirationDate,omitempty"`
+	HostOnly       bool    `json:"hostOnly"`
+	HttpOnly       bool    `json:"httpOnly"`
+	Name           string  `json:"name"`
+	Path           string  `json:"path"`
+	SameSite       string  `json:"sameSite,omitempty"`
+	Secure         bool    `json:"secure"`
+	Session        bool    `json:"session"`
+	Value          string  `json:"value"`
+}
+
+// netscapeHeader is the first line of every cookies.txt file.
+const netscapeHeader string = "# Netscape HTTP Cookie File"
+
+var jsonSameSite = map[SameSite]string{
+	SameSiteDefaultMode: "unspecified",
+	SameSiteLaxMode:     "lax",
+	SameSiteNoneMode:    "no_restriction",
+	SameSiteStrictMode:  "strict",
+}
+
+// cookieExpired will return true if the cookie has an expiry that
+// has passed. Session cookies never expire.
+func cookieExpired(c *Cookie, now time.Time) bool {
+	return !c.Expires.IsZero() && !c.Expires.After(now)
+}
+
+// ReadJSONCookies will read cookies from a JSON array, as written by
+// WriteJSONCookies or by common browser cookie exporters. Same as
+// curl, expired cookies are dropped and session cookies are kept.
+func ReadJSONCookies(r io.Reader) ([]*Cookie, error) {
+	var c *Cookie
+	var cookies []*Cookie
+	var e error
+	var in []jsonCookie
+	var now time.Time = time.Now()
+
+	if e = json.NewDecoder(r).Decode(&in); e != nil {
+		return nil, errors.Newf("failed to parse cookies: %w", e)
+	}
+
+	for _, jc := range in {
+		if (jc.Name == "") || (jc.Domain == "") {
+			continue
+		}
+
+		c = &Cookie{
+			Domain:   strings.ToLower(strings.TrimPrefix(jc.Domain, ".")),
+			HostOnly: jc.HostOnly,
+			HttpOnly: jc.HttpOnly,
+			Name:     jc.Name,
+			Path:     jc.Path,
+			Secure:   jc.Secure,
+			Value:    jc.Value,
+		}
+
+		if c.Path == "" {
+			c.Path = "/"
+		}
+
+		if !jc.Session && (jc.ExpirationDate > 0) {
+			c.Expires = time.Unix(int64(jc.ExpirationDate), 0)
+		}
+
+		for mode, str := range jsonSameSite {
+			if strings.EqualFold(jc.SameSite, str) {
+				c.SameSite = mode
+			}
+		}
+
+		if !cookieExpired(c, now) {
+			cookies = append(cookies, c)
+		}
+	}
+
+	return cookies, nil
+}
+
+// ReadNetscapeCookies will read cookies in the Netscape cookies.txt
+// format used by curl, wget, and browser extensions. Same as curl,
+// lines prefixed with "#HttpOnly_" are HttpOnly cookies, malformed
+// lines are skipped, expired cookies are dropped, and session cookies
+// (expiry of 0) are kept.
+func ReadNetscapeCookies(r io.Reader) ([]*Cookie, error) {
+	var c *Cookie
+	var cookies []*Cookie
+	var e error
+	var expires int64
+	var fields []string
+	var httpOnly bool
+	var line string
+	var now time.Time = time.Now()
+	var scanner *bufio.Scanner = bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		line = strings.TrimRight(scanner.Text(), "\r")
+
+		httpOnly = strings.HasPrefix(line, "#HttpOnly_")
+		if httpOnly {
+			line = strings.TrimPrefix(line, "#HttpOnly_")
+		}
+
+		if (strings.TrimSpace(line) == "") || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Value may be empty (and the trailing tab missing)
+		if fields = strings.Split(line, "\t"); len(fields) == 6 {
+			fields = append(fields, "")
+		}
+
+		// Skip malformed lines
+		if len(fields) != 7 {
+			continue
+		}
+
+		expires, e = strconv.ParseInt(fields[4], 10, 64)
+		if e != nil {
+			continue
+		}
+
+		c = &Cookie{
+			Domain:   strings.ToLower(strings.TrimPrefix(fields[0], ".")),
+			HostOnly: !strings.EqualFold(fields[1], "TRUE"),
+			HttpOnly: httpOnly,
+			Name:     fields[5],
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Value:    fields[6],
+		}
+
+		if expires > 0 {
+			c.Expires = time.Unix(expires, 0)
+		}
+
+		if (c.Domain != "") && !cookieExpired(c, now) {
+			cookies = append(cookies, c)
+		}
+	}
+
+	if e = scanner.Err(); e != nil {
+		return nil, errors.Newf("failed to read cookies: %w", e)
+	}
+
+	return cookies, nil
+}
+
+// WriteJSONCookies will write cookies as a JSON array. Same as curl,
+// expired cookies and cookies w/o a Domain are skipped.
+func WriteJSONCookies(w io.Writer, cookies []*Cookie) error {
+	var enc *json.Encoder = json.NewEncoder(w)
+	var jc jsonCookie
+	var now time.Time = time.Now()
+	var out []jsonCookie = []jsonCookie{}
+
+	for _, c := range cookies {
+		if (c.Domain == "") || cookieExpired(c, now) {
+			continue
+		}
+
+		jc = jsonCookie{
+			Domain:   c.Domain,
+			HostOnly: c.HostOnly,
+			HttpOnly: c.HttpOnly,
+			Name:     c.Name,
+			Path:     c.Path,
+			SameSite: jsonSameSite[c.SameSite],
+			Secure:   c.Secure,
+			Session:  c.Expires.IsZero(),
+			Value:    c.Value,
+		}
+
+		if !c.HostOnly {
+			jc.Domain = "." + jc.Domain
+		}
+
+		if !jc.Session {
+			jc.ExpirationDate = float64(c.Expires.Unix())
+		}
+
+		out = append(out, jc)
+	}
+
+	enc.SetIndent("", "  ")
+
+	if e := enc.Encode(out); e != nil {
+		return errors.Newf("failed to write cookies: %w", e)
+	}
+
+	return nil
+}
+
+// WriteNetscapeCookies will write cookies in the Netscape
+// cookies.txt format. Same as curl, expired cookies and cookies w/o
+// a Domain are skipped, and session cookies have an expiry of 0.
+func WriteNetscapeCookies(w io.Writer, cookies []*Cookie) error {
+	var domain string
+	var e error
+	var expires int64
+	var now time.Time = time.Now()
+	var path string
+	var subdomains string
+	var sb strings.Builder
+
+	sb.WriteString(netscapeHeader + "\n\n")
+
+	for _, c := range cookies {
+		if (c.Domain == "") || cookieExpired(c, now) {
+			continue
+		}
+
+		domain = c.Domain
+		expires = 0
+		path = c.Path
+		subdomains = "FALSE"
+
+		if !c.HostOnly {
+			domain = "." + domain
+			subdomains = "TRUE"
+		}
+
+		if c.HttpOnly {
+			domain = "#HttpOnly_" + domain
+		}
+
+		if !c.Expires.IsZero() {
+			expires = c.Expires.Unix()
+		}
+
+		if path == "" {
+			path = "/"
+		}
+
+		sb.WriteString(
+			strings.Join(
+				[]string{
+					domain,
+					subdomains,
+					path,
+					strings.ToUpper(strconv.FormatBool(c.Secure)),
+					strconv.FormatInt(expires, 10),
+					c.Name,
+					c.Value,
+				},
+				"\t",
+			) + "\n",
+		)
+	}
+
+	if _, e = io.WriteString(w, sb.String()); e != nil {
+		return errors.Newf("failed to write cookies: %w", e)
+	}
+
+	return nil
+}
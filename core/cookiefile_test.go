@@ -0,0 +1,56 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadNetscapeCookies(t *testing.T) {
+	var cookies []*Cookie
+	var e error
+	var in string = strings.Join(
+		[]string{
+			netscapeHeader,
+			"",
+			"# Comment",
+			".example.com\tTRUE\t/\tFALSE\t0\tsession\tabc",
+			"#HttpOnly_example.com\tFALSE\t/api\tTRUE\t0\thttponly\t1",
+			"example.com\tFALSE\t/\tFALSE\t0\tempty",
+			"example.com\tFALSE\t/\tFALSE\t1\texpired\tx",
+			"malformed line w/o tabs",
+			"example.com\tFALSE\t/\tFALSE\tnever\tbadexpiry\tx",
+			"example.com\tFALSE\t/\tFALSE\t0\ttoo\tmany\tfields",
+			"example.com\tFALSE\t/\tFALSE\t4102444800\tlater\ty",
+		},
+		"\r\n",
+	)
+	var names []string
+
+	if cookies, e = ReadNetscapeCookies(strings.NewReader(in)); e != nil {
+		t.Fatal(e)
+	}
+
+	for _, c := range cookies {
+		names = append(names, c.Name)
+	}
+
+	if strings.Join(names, ",") != "session,httponly,empty,later" {
+		t.Fatalf("got cookies %v", names)
+	}
+
+	if cookies[0].HostOnly || (cookies[0].Domain != "example.com") {
+		t.Errorf("got %+v, expected domain cookie", cookies[0])
+	}
+
+	if !cookies[1].HttpOnly || !cookies[1].Secure || !cookies[1].HostOnly {
+		t.Errorf("got %+v, expected HttpOnly, Secure cookie", cookies[1])
+	}
+
+	if cookies[2].Value != "" {
+		t.Errorf("got value %q, expected empty", cookies[2].Value)
+	}
+
+	if cookies[3].Expires.Unix() != 4102444800 {
+		t.Errorf("got expiry %s", cookies[3].Expires)
+	}
+}
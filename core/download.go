@@ -0,0 +1,480 @@
+package core
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	goerrors "errors"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mjwhitta/win/errors"
+)
+
+// DefaultDownloadRetries is the number of times a dropped download is
+// resumed by default.
+const DefaultDownloadRetries int = 5
+
+// ErrChecksum is returned by Client.Download when the downloaded file
+// doesn't match the expected SHA-256 digest.
+var ErrChecksum = errors.New("checksum mismatch")
+
+// DownloadOptions configures Client.Download and
+// Client.DownloadSegmented.
+type DownloadOptions struct {
+	// Retries is the number of times a dropped transfer is resumed
+	// before failing. Responses w/ a status code retried by the
+	// Client's RetryPolicy (DefaultRetryStatusCodes if nil) are
+	// retried as well, other non-2xx responses are final. If 0,
+	// DefaultDownloadRetries is used. If negative, transfers are not
+	// resumed (though a partial file is still resumed by the next
+	// call).
+	Retries int
+
+	// SHA256 is the expected hex-encoded SHA-256 digest of the file.
+	// If empty, the file is not verified.
+	SHA256 string
+
+	// Segments is the number of byte ranges Client.DownloadSegmented
+	// fetches at the same time. If 0, DefaultSegments is used. It is
+	// ignored by Client.Download.
+	Segments int
+}
+
+// download is the state of a single call to Client.Download.
+type download struct {
+	c      *Client
+	ctx    context.Context
+	f      *os.File
+	offset int64
+	opts   DownloadOptions
+	path   string
+	state  downloadState
+	total  int64
+	url    string
+}
+
+// downloadState is saved alongside a partial download, so it can be
+// resumed later.
+type downloadState struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	URL          string `json:"url"`
+}
+
+// writeError marks an error writing to the temporary file, which
+// stops a download from being resumed.
+type writeError struct {
+	error
+}
+
+// parseContentRange will parse a Content-Range header. The total is
+// -1 if unknown. The start and end are -1 for an unsatisfied range.
+func parseContentRange(val string) (int64, int64, int64, bool) {
+	var e error
+	var end int64 = -1
+	var rng string
+	var start int64 = -1
+	var total int64 = -1
+	var tmp []string
+
+	if !strings.HasPrefix(val, "bytes ") {
+		return 0, 0, 0, false
+	}
+
+	if tmp = strings.SplitN(val[len("bytes "):], "/", 2); len(tmp) != 2 {
+		return 0, 0, 0, false
+	}
+
+	if rng = strings.TrimSpace(tmp[0]); tmp[1] != "*" {
+		total, e = strconv.ParseInt(strings.TrimSpace(tmp[1]), 10, 64)
+		if (e != nil) || (total < 0) {
+			return 0, 0, 0, false
+		}
+	}
+
+	if rng == "*" {
+		return start, end, total, total >= 0
+	}
+
+	if tmp = strings.SplitN(rng, "-", 2); len(tmp) != 2 {
+		return 0, 0, 0, false
+	}
+
+	if start, e = strconv.ParseInt(tmp[0], 10, 64); e != nil {
+		return 0, 0, 0, false
+	}
+
+	if end, e = strconv.ParseInt(tmp[1], 10, 64); e != nil {
+		return 0, 0, 0, false
+	}
+
+	if (start < 0) || (end < start) || ((total >= 0) && (end >= total)) {
+		return 0, 0, 0, false
+	}
+
+	return start, end, total, true
+}
+
+// Download will save the resource at the provided URL to a file. The
+// data is written to a temporary file (path + ".part"), which is only
+// renamed to path once complete and verified. If the transfer drops,
+// it is resumed w/ a Range request, using If-Range w/ the ETag or
+// Last-Modified header to ensure the resource has not changed. The
+// partial file and its validators are kept on failure, so a later
+// call resumes where it left off. If opts is nil, the defaults are
+// used.
+func (c *Client) Download(
+	ctx context.Context,
+	url string,
+	path string,
+	opts *DownloadOptions,
+) error {
+	var d *download = &download{
+		c:     c,
+		ctx:   ctx,
+		path:  path,
+		total: -1,
+		url:   url,
+	}
+	var e error
+
+	if opts != nil {
+		d.opts = *opts
+	}
+
+	if e = d.open(); e != nil {
+		return e
+	}
+	defer d.f.Close()
+
+	if e = d.retry(ctx, d.attempt); e != nil {
+		// Nothing to resume
+		if d.offset == 0 {
+			d.f.Close()
+			os.Remove(d.f.Name())
+		}
+
+		return e
+	}
+
+	return d.finish()
+}
+
+// attempt will make a single request, writing the response body to
+// the temporary file. It returns whether the download is complete.
+func (d *download) attempt() (bool, error) {
+	var e error
+	var ok bool
+	var r *Request = NewRequestWithContext(d.ctx, MethodGet, d.url)
+	var res *Response
+	var start int64
+	var total int64
+	var validator string = d.validator()
+
+	// Ranges apply to the encoded representation, so never request
+	// compression
+	r.Headers.Set("Accept-Encoding", "identity")
+
+	if (d.offset > 0) && (validator != "") {
+		r.Headers.Set("Range", "bytes="+strconv.FormatInt(d.offset, 10)+"-")
+		r.Headers.Set("If-Range", validator)
+	} else if d.offset > 0 {
+		// Can't safely resume w/o a validator
+		if e = d.reset(); e != nil {
+			return false, e
+		}
+	}
+
+	if res, e = d.c.Do(r); e != nil {
+		return false, e
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		if e = d.reset(); e != nil {
+			return false, e
+		}
+
+		d.total = res.ContentLength
+	case http.StatusPartialContent:
+		start, _, total, ok = parseContentRange(
+			res.Header.Get("Content-Range"),
+		)
+		if !ok || (start != d.offset) {
+			return false, errors.Newf(
+				"unexpected Content-Range %s",
+				res.Header.Get("Content-Range"),
+			)
+		}
+
+		d.total = total
+	case http.StatusRequestedRangeNotSatisfiable:
+		_, _, total, ok = parseContentRange(
+			res.Header.Get("Content-Range"),
+		)
+
+		// Already complete
+		if ok && (total == d.offset) {
+			d.total = total
+			return true, nil
+		}
+
+		// Start over
+		return false, d.reset()
+	default:
+		return false, newStatusError(res)
+	}
+
+	d.state = downloadState{
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+		URL:          d.url,
+	}
+
+	if e = d.save(); e != nil {
+		return false, e
+	}
+
+	if _, e = d.f.Seek(d.offset, io.SeekStart); e != nil {
+		return false, errors.Newf("failed to seek %s: %w", d.f.Name(), e)
+	}
+
+	e = d.copy(res.Body)
+
+	if (e == nil) && (d.total >= 0) && (d.offset < d.total) {
+		e = io.ErrUnexpectedEOF
+	}
+
+	return e == nil, e
+}
+
+// copy will append the response body to the temporary file. Write
+// errors are wrapped in a writeError, as they can't be resumed.
+func (d *download) copy(body io.Reader) error {
+	var b []byte = make([]byte, 32<<10)
+	var e error
+	var n int
+
+	for {
+		n, e = body.Read(b)
+
+		if n > 0 {
+			if _, err := d.f.Write(b[:n]); err != nil {
+				return &writeError{
+					errors.Newf("failed to write %s: %w", d.f.Name(), err),
+				}
+			}
+
+			d.offset += int64(n)
+		}
+
+		if goerrors.Is(e, io.EOF) {
+			return nil
+		} else if e != nil {
+			return errors.Newf("failed to read body: %w", e)
+		}
+	}
+}
+
+// finish will verify the temporary file and rename it.
+func (d *download) finish() error {
+	var digest string
+	var e error
+	var h hash.Hash = sha256.New()
+
+	if d.opts.SHA256 != "" {
+		if _, e = d.f.Seek(0, io.SeekStart); e != nil {
+			return errors.Newf("failed to seek %s: %w", d.f.Name(), e)
+		}
+
+		if _, e = io.Copy(h, d.f); e != nil {
+			return errors.Newf("failed to read %s: %w", d.f.Name(), e)
+		}
+
+		digest = hex.EncodeToString(h.Sum(nil))
+
+		if !strings.EqualFold(digest, d.opts.SHA256) {
+			// Corrupt, so don't resume
+			d.f.Close()
+			os.Remove(d.f.Name())
+			os.Remove(d.statePath())
+
+			return errors.Newf(
+				"%w: got sha256 %s, expected %s",
+				ErrChecksum,
+				digest,
+				d.opts.SHA256,
+			)
+		}
+	}
+
+	if e = d.f.Sync(); e != nil {
+		return errors.Newf("failed to sync %s: %w", d.f.Name(), e)
+	}
+
+	if e = d.f.Close(); e != nil {
+		return errors.Newf("failed to close %s: %w", d.f.Name(), e)
+	}
+
+	if e = os.Rename(d.f.Name(), d.path); e != nil {
+		return errors.Newf("failed to rename %s: %w", d.f.Name(), e)
+	}
+
+	os.Remove(d.statePath())
+
+	return nil
+}
+
+// open will open the temporary file and load the state of a previous
+// partial download, if any.
+func (d *download) open() error {
+	var b []byte
+	var e error
+	var fi os.FileInfo
+
+	d.f, e = os.OpenFile(d.path+".part", os.O_CREATE|os.O_RDWR, 0o644)
+	if e != nil {
+		return errors.Newf("failed to open %s.part: %w", d.path, e)
+	}
+
+	if fi, e = d.f.Stat(); e != nil {
+		d.f.Close()
+		return errors.Newf("failed to stat %s: %w", d.f.Name(), e)
+	}
+
+	// State is only used if it is for the same URL
+	if b, e = os.ReadFile(d.statePath()); e == nil {
+		if json.Unmarshal(b, &d.state) == nil && (d.state.URL == d.url) {
+			d.offset = fi.Size()
+		}
+	}
+
+	return nil
+}
+
+// reset will truncate the temporary file and forget the state.
+func (d *download) reset() error {
+	var e error
+
+	d.offset = 0
+	d.state = downloadState{}
+
+	if e = d.f.Truncate(0); e != nil {
+		return errors.Newf("failed to truncate %s: %w", d.f.Name(), e)
+	}
+
+	os.Remove(d.statePath())
+
+	return nil
+}
+
+// retry will call attempt until the download (or segment) is
+// complete, backing off between failed attempts. An attempt that
+// fails w/o an error (e.g. it had to start over) is retried
+// immediately.
+func (d *download) retry(
+	ctx context.Context,
+	attempt func() (bool, error),
+) error {
+	var done bool
+	var e error
+	var p *RetryPolicy = d.c.RetryPolicy
+	var res *Response
+	var retries int = d.opts.Retries
+	var se *StatusError
+	var timer *time.Timer
+	var we *writeError
+
+	if p == nil {
+		p = &RetryPolicy{}
+	}
+
+	if retries == 0 {
+		retries = DefaultDownloadRetries
+	}
+
+	for retry := 0; ; retry++ {
+		if done, e = attempt(); done {
+			return nil
+		}
+
+		// Honor Retry-After
+		res = nil
+		se = nil
+
+		if goerrors.As(e, &se) {
+			res = &Response{Header: se.Header}
+		}
+
+		// Write errors, non-retryable status codes, and the caller
+		// giving up are final
+		if goerrors.As(e, &we) {
+			return we.error
+		} else if (se != nil) && !p.retryStatus(se.StatusCode) {
+			return e
+		} else if goerrors.Is(e, errRangeIgnored) {
+			return e
+		} else if ctx.Err() != nil {
+			return errors.Newf("download aborted: %w", ctx.Err())
+		} else if retry >= retries {
+			if e == nil {
+				e = errors.New("download incomplete")
+			}
+
+			return e
+		}
+
+		// No delay after a reset
+		if e == nil {
+			continue
+		}
+
+		timer = time.NewTimer(p.backoff(retry, res))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Newf("download aborted: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// save will write the state alongside the temporary file.
+func (d *download) save() error {
+	var b []byte
+	var e error
+
+	if b, e = json.Marshal(d.state); e != nil {
+		return errors.Newf("failed to save state: %w", e)
+	}
+
+	if e = os.WriteFile(d.statePath(), b, 0o644); e != nil {
+		return errors.Newf("failed to save state: %w", e)
+	}
+
+	return nil
+}
+
+// statePath will return the path of the saved state.
+func (d *download) statePath() string {
+	return d.path + ".part.json"
+}
+
+// validator will return the If-Range value of the partial file, or
+// empty if it can't be resumed. Weak ETags can't be used w/ If-Range.
+func (d *download) validator() string {
+	if (d.state.ETag != "") && !strings.HasPrefix(d.state.ETag, "W/") {
+		return d.state.ETag
+	}
+
+	return d.state.LastModified
+}
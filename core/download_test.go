@@ -0,0 +1,221 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	goerrors "errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testDownload will return a Client and a server that serves the
+// provided content at /file, failing the first n requests w/ the
+// provided status code.
+func testDownload(
+	t *testing.T,
+	content []byte,
+	code int,
+	n int64,
+) (*Client, string, *atomic.Int64) {
+	var b Backend
+	var e error
+	var reqs atomic.Int64
+	var srv *httptest.Server
+
+	srv = httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if (r.Method == http.MethodGet) && (reqs.Add(1) <= n) {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(code)
+					return
+				}
+
+				http.ServeContent(
+					w,
+					r,
+					"file",
+					time.Unix(0, 0),
+					bytes.NewReader(content),
+				)
+			},
+		),
+	)
+	t.Cleanup(srv.Close)
+
+	if b, e = NewStd("test", ""); e != nil {
+		t.Fatal(e)
+	}
+
+	return NewClient(b), srv.URL + "/file", &reqs
+}
+
+func TestDownload(t *testing.T) {
+	var c *Client
+	var content []byte = bytes.Repeat([]byte("0123456789"), 1000)
+	var e error
+	var out []byte
+	var path string = filepath.Join(t.TempDir(), "file")
+	var reqs *atomic.Int64
+	var se *StatusError
+	var url string
+
+	// Retryable status codes are retried
+	c, url, reqs = testDownload(
+		t,
+		content,
+		http.StatusServiceUnavailable,
+		2,
+	)
+	defer c.Close()
+
+	if e = c.Download(context.Background(), url, path, nil); e != nil {
+		t.Fatal(e)
+	}
+
+	if out, e = os.ReadFile(path); e != nil {
+		t.Fatal(e)
+	} else if !bytes.Equal(out, content) {
+		t.Error("content mismatch")
+	}
+
+	if n := reqs.Load(); n != 3 {
+		t.Errorf("got %d requests, expected 3", n)
+	}
+
+	// Other status codes are final, and w/ nothing to resume, no
+	// partial file is left behind
+	path = filepath.Join(t.TempDir(), "file")
+	c, url, reqs = testDownload(t, content, http.StatusNotFound, 1)
+	defer c.Close()
+
+	e = c.Download(context.Background(), url, path, nil)
+	if !goerrors.As(e, &se) || (se.StatusCode != http.StatusNotFound) {
+		t.Fatalf("got %v, expected 404 StatusError", e)
+	}
+
+	if n := reqs.Load(); n != 1 {
+		t.Errorf("got %d requests, expected 1", n)
+	}
+
+	if _, e = os.Stat(path + ".part"); !os.IsNotExist(e) {
+		t.Errorf("%s.part was left behind", path)
+	}
+}
+
+// TestDownloadResume checks that a dropped transfer is resumed w/ a
+// Range request, and that the checksum is verified.
+func TestDownloadResume(t *testing.T) {
+	var b Backend
+	var c *Client
+	var content []byte = bytes.Repeat([]byte("0123456789"), 1000)
+	var digest [32]byte = sha256.Sum256(content)
+	var dir string = t.TempDir()
+	var e error
+	var files []os.DirEntry
+	var mutex sync.Mutex
+	var out []byte
+	var path string = filepath.Join(dir, "file")
+	var ranges []string
+	var srv *httptest.Server
+
+	srv = httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				mutex.Lock()
+				ranges = append(ranges, r.Header.Get("Range"))
+				mutex.Unlock()
+
+				// Drop the first transfer halfway
+				if r.Header.Get("Range") == "" {
+					w.Header().Set("ETag", `"v1"`)
+					w.Header().Set(
+						"Content-Length",
+						strconv.Itoa(len(content)),
+					)
+					w.Write(content[:len(content)/2])
+					w.(http.Flusher).Flush()
+					panic(http.ErrAbortHandler)
+				}
+
+				if r.Header.Get("If-Range") != `"v1"` {
+					t.Errorf("got If-Range %q", r.Header.Get("If-Range"))
+				}
+
+				w.Header().Set("ETag", `"v1"`)
+				http.ServeContent(
+					w,
+					r,
+					"file",
+					time.Unix(0, 0),
+					bytes.NewReader(content),
+				)
+			},
+		),
+	)
+	defer srv.Close()
+
+	if b, e = NewStd("test", ""); e != nil {
+		t.Fatal(e)
+	}
+
+	c = NewClient(b)
+	defer c.Close()
+
+	e = c.Download(
+		context.Background(),
+		srv.URL,
+		path,
+		&DownloadOptions{SHA256: hex.EncodeToString(digest[:])},
+	)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	if out, e = os.ReadFile(path); e != nil {
+		t.Fatal(e)
+	} else if !bytes.Equal(out, content) {
+		t.Error("content mismatch")
+	}
+
+	mutex.Lock()
+	if (len(ranges) != 2) || (ranges[1] != "bytes=5000-") {
+		t.Errorf("got ranges %q", ranges)
+	}
+	mutex.Unlock()
+
+	// Only the file is left
+	if files, e = os.ReadDir(dir); e != nil {
+		t.Fatal(e)
+	} else if len(files) != 1 {
+		t.Errorf("got files %v", files)
+	}
+
+	// Checksum mismatch, nothing is kept
+	os.Remove(path)
+
+	e = c.Download(
+		context.Background(),
+		srv.URL,
+		path,
+		&DownloadOptions{SHA256: "00"},
+	)
+	if !goerrors.Is(e, ErrChecksum) {
+		t.Errorf("got %v, expected ErrChecksum", e)
+	}
+
+	if files, e = os.ReadDir(dir); e != nil {
+		t.Fatal(e)
+	} else if len(files) != 0 {
+		t.Errorf("got files %v", files)
+	}
+}
@@ -0,0 +1,286 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/mjwhitta/win/errors"
+)
+
+// Fake is a Backend that dispatches each Request to a registered
+// handler in memory, w/o any sockets or DLLs. It allows code built on
+// a Client (including its redirect, retry, and cookie handling) to be
+// unit tested on any platform:
+//
+//	var c *core.Client
+//	var f *core.Fake = core.NewFake()
+//
+//	f.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+//		w.Write([]byte("hello"))
+//	})
+//	c = core.NewClient(f)
+//
+// Handlers are matched by a net/http.ServeMux, so patterns may
+// include a host. Requests w/ no matching handler get a 404 response.
+type Fake struct {
+	mux   *http.ServeMux
+	mutex sync.RWMutex
+}
+
+// fakeHandler is the http.Handler registered for a RoundTripFunc, so
+// it can be found by the net/http.ServeMux and called directly.
+type fakeHandler RoundTripFunc
+
+// fakeWriter is an in-memory net/http.ResponseWriter.
+type fakeWriter struct {
+	body   bytes.Buffer
+	header http.Header
+	sent   http.Header
+	status int
+}
+
+// NewFake will return a pointer to a new Fake instance with no
+// handlers.
+func NewFake() *Fake {
+	return &Fake{mux: http.NewServeMux()}
+}
+
+// fakeResponse will fill in any fields missing from a Response
+// returned by a RoundTripFunc.
+func fakeResponse(res *Response) *Response {
+	if res.Body == nil {
+		res.Body = http.NoBody
+	}
+
+	if res.Header == nil {
+		res.Header = Header{}
+	}
+
+	if res.Proto == "" {
+		res.Proto = "HTTP/1.1"
+		res.ProtoMajor = 1
+		res.ProtoMinor = 1
+	}
+
+	if res.StatusCode == 0 {
+		res.StatusCode = http.StatusOK
+	}
+
+	if res.Status == "" {
+		res.Status = strconv.Itoa(res.StatusCode) + " " +
+			http.StatusText(res.StatusCode)
+	}
+
+	// Parse cookies, unless already provided
+	if len(res.Cookies()) == 0 {
+		for _, raw := range res.Header.Values("Set-Cookie") {
+			// Skip malformed cookies
+			if c := parseSetCookie(raw); c != nil {
+				res.AddCookie(c)
+			}
+		}
+	}
+
+	return res
+}
+
+// buildRequest will convert the Request into a server-side
+// net/http.Request, as seen by a net/http.Handler.
+func (f *Fake) buildRequest(
+	ctx context.Context,
+	r *Request,
+) (*http.Request, error) {
+	var body io.ReadCloser = http.NoBody
+	var e error
+	var req *http.Request
+	var uri *url.URL
+
+	if uri, e = url.Parse(r.URL); e != nil {
+		return nil, errors.Newf("failed to parse url: %w", e)
+	}
+
+	if r.Body != nil {
+		body = io.NopCloser(r.Body)
+	}
+
+	req, e = http.NewRequestWithContext(ctx, r.Method, r.URL, body)
+	if e != nil {
+		return nil, errors.Newf("failed to open request: %w", e)
+	}
+
+	req.ContentLength = r.outgoingLength()
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.RequestURI = uri.RequestURI()
+
+	if uri.Scheme == "https" {
+		req.TLS = &tls.ConnectionState{
+			HandshakeComplete: true,
+			ServerName:        uri.Hostname(),
+		}
+	}
+
+	// Process cookies
+	for _, c := range r.Cookies() {
+		req.AddCookie(&http.Cookie{Name: c.Name, Value: c.Value})
+	}
+
+	// Process headers
+	for k, vs := range r.Headers {
+		req.Header[k] = append([]string(nil), vs...)
+	}
+
+	if vs, ok := req.Header["Host"]; ok && (len(vs) > 0) {
+		req.Host = vs[0]
+		delete(req.Header, "Host")
+	}
+
+	return req, nil
+}
+
+// Handle will register the http.Handler for the provided pattern,
+// same as net/http.ServeMux.
+func (f *Fake) Handle(pattern string, h http.Handler) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.mux.Handle(pattern, h)
+}
+
+// HandleFunc will register the handler function for the provided
+// pattern, same as net/http.ServeMux.
+func (f *Fake) HandleFunc(
+	pattern string,
+	h func(w http.ResponseWriter, r *http.Request),
+) {
+	f.Handle(pattern, http.HandlerFunc(h))
+}
+
+// HandleRequest will register the RoundTripFunc for the provided
+// pattern. It is given the Request as sent by the Client and its
+// Response is returned as is, aside from filling in any missing
+// Header, Proto, or Status. An error is returned from Client.Do, so
+// connection failures can be simulated.
+func (f *Fake) HandleRequest(pattern string, h RoundTripFunc) {
+	f.Handle(pattern, fakeHandler(h))
+}
+
+// Send will dispatch the Request to the matching handler.
+func (f *Fake) Send(c *Client, r *Request) (*Response, error) {
+	var cancel context.CancelFunc
+	var ctx context.Context = r.Context()
+	var e error
+	var h http.Handler
+	var req *http.Request
+	var res *Response
+	var w *fakeWriter = &fakeWriter{header: http.Header{}}
+
+	if c.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	if req, e = f.buildRequest(ctx, r); e != nil {
+		return nil, e
+	}
+
+	f.mutex.RLock()
+	h, _ = f.mux.Handler(req)
+	f.mutex.RUnlock()
+
+	if fn, ok := h.(fakeHandler); ok {
+		res, e = fn(r.WithContext(ctx))
+	} else {
+		h.ServeHTTP(w, req)
+		res = w.response(req)
+	}
+
+	if e != nil {
+		return nil, errors.Newf("failed to send request: %w", e)
+	} else if res == nil {
+		return nil, errors.New("no response returned")
+	}
+
+	res = fakeResponse(res)
+
+	if e = ctx.Err(); e != nil {
+		res.Body.Close()
+		return nil, errors.Newf("failed to send request: %w", e)
+	}
+
+	return res, nil
+}
+
+func (h fakeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Never called, Fake calls the RoundTripFunc directly
+	http.Error(w, "unsupported", http.StatusNotImplemented)
+}
+
+// Header will return the response headers.
+func (w *fakeWriter) Header() http.Header {
+	return w.header
+}
+
+// response will convert the written response into a Response, adding
+// the headers a net/http.Server would.
+func (w *fakeWriter) response(req *http.Request) *Response {
+	var b []byte
+	var n int64
+
+	w.WriteHeader(http.StatusOK)
+
+	if req.Method == MethodHead {
+		w.body.Reset()
+	}
+
+	b = w.body.Bytes()
+	n = int64(len(b))
+
+	if (w.sent.Get("Content-Type") == "") && (len(b) > 0) {
+		w.sent.Set("Content-Type", http.DetectContentType(b))
+	}
+
+	if (w.sent.Get("Content-Length") == "") && (req.Method != MethodHead) {
+		w.sent.Set("Content-Length", strconv.Itoa(len(b)))
+	}
+
+	// HEAD responses report the length of the body that would have
+	// been sent, if known
+	if req.Method == MethodHead {
+		n = contentLength(Header(w.sent))
+	}
+
+	return &Response{
+		Body:          io.NopCloser(bytes.NewReader(b)),
+		ContentLength: n,
+		Header:        Header(w.sent),
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Status: strconv.Itoa(w.status) + " " +
+			http.StatusText(w.status),
+		StatusCode: w.status,
+	}
+}
+
+// Write will buffer the response body.
+func (w *fakeWriter) Write(b []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(b)
+}
+
+// WriteHeader will set the response status code. Same as a
+// net/http.Server, headers modified afterwards are ignored.
+func (w *fakeWriter) WriteHeader(code int) {
+	if w.sent != nil {
+		return
+	}
+
+	w.sent = w.header.Clone()
+	w.status = code
+}
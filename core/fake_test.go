@@ -0,0 +1,235 @@
+package core
+
+import (
+	"context"
+	goerrors "errors"
+	"io"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	var c *Client
+	var e error
+	var f *Fake = NewFake()
+	var out []byte
+	var req *Request
+	var res *Response
+
+	f.HandleFunc(
+		"/echo",
+		func(w http.ResponseWriter, r *http.Request) {
+			var b []byte
+
+			b, _ = io.ReadAll(r.Body)
+
+			http.SetCookie(w, &http.Cookie{Name: "a", Value: "1"})
+			w.Header().Set("X-Host", r.Host)
+			w.Header().Set("X-TLS", strings.ToLower(r.URL.Scheme))
+
+			if r.TLS != nil {
+				w.Header().Set("X-TLS", r.TLS.ServerName)
+			}
+
+			w.WriteHeader(http.StatusCreated)
+
+			// Ignored after WriteHeader
+			w.Header().Set("X-Late", "1")
+
+			w.Write([]byte(r.Method + "|" + r.Header.Get("X-Test") + "|"))
+			w.Write(b)
+		},
+	)
+	f.HandleFunc(
+		"/redirect",
+		func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/echo", http.StatusFound)
+		},
+	)
+
+	c = NewClient(f)
+	c.Jar = NewJar()
+
+	req = NewRequest(MethodPut, "https://example.com/echo", []byte("body"))
+	req.Headers.Set("Host", "other.com")
+	req.Headers.Set("X-Test", "a")
+
+	if res, e = c.Do(req); e != nil {
+		t.Fatal(e)
+	}
+
+	out, _ = io.ReadAll(res.Body)
+	res.Body.Close()
+
+	if string(out) != "PUT|a|body" {
+		t.Errorf("got %q, expected %q", out, "PUT|a|body")
+	}
+
+	for k, expected := range map[string]string{
+		"Content-Length": "10",
+		"Content-Type":   "text/plain; charset=utf-8",
+		"X-Host":         "other.com",
+		"X-Late":         "",
+		"X-TLS":          "example.com",
+	} {
+		if v := res.Header.Get(k); v != expected {
+			t.Errorf("got %s %q, expected %q", k, v, expected)
+		}
+	}
+
+	if (res.StatusCode != http.StatusCreated) || (res.ContentLength != 10) {
+		t.Errorf("got %s, %d", res.Status, res.ContentLength)
+	}
+
+	if (len(res.Cookies()) != 1) || (res.Cookies()[0].Name != "a") {
+		t.Errorf("got cookies %v, expected [a=1]", res.Cookies())
+	}
+
+	// Redirects are followed by the Client
+	if res, e = c.Get("http://example.com/redirect"); e != nil {
+		t.Fatal(e)
+	}
+
+	out, _ = io.ReadAll(res.Body)
+	res.Body.Close()
+
+	if string(out) != "GET||" {
+		t.Errorf("got %q, expected %q", out, "GET||")
+	}
+
+	// No handler
+	if res, e = c.Get("http://example.com/missing"); e != nil {
+		t.Fatal(e)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("got status %d, expected 404", res.StatusCode)
+	}
+}
+
+func TestFakeHead(t *testing.T) {
+	var c *Client
+	var e error
+	var f *Fake = NewFake()
+	var out []byte
+	var res *Response
+
+	f.HandleFunc(
+		"/known",
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", "1234")
+		},
+	)
+	f.HandleFunc(
+		"/unknown",
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("dropped"))
+		},
+	)
+
+	c = NewClient(f)
+
+	for path, expected := range map[string]int64{
+		"/known":   1234,
+		"/unknown": -1,
+	} {
+		if res, e = c.Head("http://example.com" + path); e != nil {
+			t.Fatal(e)
+		}
+
+		out, _ = io.ReadAll(res.Body)
+		res.Body.Close()
+
+		if len(out) != 0 {
+			t.Errorf("%s: got body %q, expected none", path, out)
+		}
+
+		if res.ContentLength != expected {
+			t.Errorf(
+				"%s: got ContentLength %d, expected %d",
+				path,
+				res.ContentLength,
+				expected,
+			)
+		}
+	}
+}
+
+func TestFakeHandleRequest(t *testing.T) {
+	var c *Client
+	var cancel context.CancelFunc
+	var ctx context.Context
+	var e error
+	var f *Fake = NewFake()
+	var res *Response
+
+	f.HandleRequest(
+		"/reset",
+		func(r *Request) (*Response, error) {
+			return nil, syscall.ECONNRESET
+		},
+	)
+	f.HandleRequest(
+		"/nil",
+		func(r *Request) (*Response, error) {
+			return nil, nil
+		},
+	)
+	f.HandleRequest(
+		"/teapot",
+		func(r *Request) (*Response, error) {
+			return &Response{StatusCode: http.StatusTeapot}, nil
+		},
+	)
+	f.HandleRequest(
+		"/slow",
+		func(r *Request) (*Response, error) {
+			<-r.Context().Done()
+			return &Response{}, nil
+		},
+	)
+
+	c = NewClient(f)
+
+	if _, e = c.Get("http://example.com/reset"); e == nil {
+		t.Error("expected error")
+	} else if !goerrors.Is(e, syscall.ECONNRESET) {
+		t.Errorf("got %s, expected ECONNRESET", e)
+	}
+
+	if _, e = c.Get("http://example.com/nil"); e == nil {
+		t.Error("expected error for nil Response")
+	}
+
+	// Missing fields are filled in
+	if res, e = c.Get("http://example.com/teapot"); e != nil {
+		t.Fatal(e)
+	}
+	res.Body.Close()
+
+	if (res.Status != "418 I'm a teapot") || (res.Proto != "HTTP/1.1") {
+		t.Errorf("got %q, %q", res.Status, res.Proto)
+	} else if res.Header == nil {
+		t.Error("got nil Header")
+	}
+
+	// Context and Timeout are honored
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+
+	_, e = c.Do(NewRequestWithContext(ctx, MethodGet, "http://x/slow"))
+	if !goerrors.Is(e, context.Canceled) {
+		t.Errorf("got %v, expected context.Canceled", e)
+	}
+
+	c.Timeout = time.Millisecond
+
+	_, e = c.Get("http://example.com/slow")
+	if !goerrors.Is(e, context.DeadlineExceeded) {
+		t.Errorf("got %v, expected context.DeadlineExceeded", e)
+	}
+}
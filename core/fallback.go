@@ -0,0 +1,15 @@
+//go:build !windows
+
+package core
+
+// NewWinHTTP will return a Backend that uses the Go standard library,
+// as WinHTTP.dll is only available on Windows.
+func NewWinHTTP(userAgent string, proxyname string) (Backend, error) {
+	return NewStd(userAgent, proxyname)
+}
+
+// NewWinINet will return a Backend that uses the Go standard library,
+// as WinINet.dll is only available on Windows.
+func NewWinINet(userAgent string, proxyname string) (Backend, error) {
+	return NewStd(userAgent, proxyname)
+}
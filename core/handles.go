@@ -0,0 +1,15 @@
+package core
+
+import "sync/atomic"
+
+// liveHandles tracks the WinHTTP/WinINet handles (sessions,
+// connections, and requests) opened by the backends.
+var liveHandles atomic.Int64
+
+// LiveHandles will return the number of WinHTTP/WinINet handles
+// (sessions, connections, and requests) that are currently open. It
+// is intended for debugging and for tests that check for leaks. It
+// is always 0 for the Go standard library backend.
+func LiveHandles() int64 {
+	return liveHandles.Load()
+}
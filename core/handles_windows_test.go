@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestLiveHandles checks that the WinHTTP/WinINet handles are all
+// released after bodies are closed early, contexts are canceled, and
+// the Client is closed w/ requests in flight.
+func TestLiveHandles(t *testing.T) {
+	var srv *httptest.Server = httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/slow":
+					w.Write([]byte("partial"))
+					w.(http.Flusher).Flush()
+
+					select {
+					case <-r.Context().Done():
+					case <-time.After(5 * time.Second):
+					}
+				default:
+					w.Write([]byte(strings.Repeat("x", 1<<20)))
+				}
+			},
+		),
+	)
+	defer srv.Close()
+
+	for name, newBackend := range map[string]func(
+		string,
+		string,
+	) (Backend, error){
+		"WinHTTP": NewWinHTTP,
+		"WinINet": NewWinINet,
+	} {
+		t.Run(
+			name,
+			func(t *testing.T) {
+				testLiveHandles(t, srv.URL, newBackend)
+			},
+		)
+	}
+}
+
+func testLiveHandles(
+	t *testing.T,
+	url string,
+	newBackend func(string, string) (Backend, error),
+) {
+	var b Backend
+	var before int64 = LiveHandles()
+	var c *Client
+	var cancel context.CancelFunc
+	var ctx context.Context
+	var e error
+	var inflight *Response
+	var res *Response
+
+	if b, e = newBackend("test", ""); e != nil {
+		t.Fatal(e)
+	}
+
+	c = NewClient(b)
+
+	// Body closed before EOF
+	for i := 0; i < 3; i++ {
+		if res, e = c.Get(url + "/big"); e != nil {
+			t.Fatal(e)
+		}
+
+		io.CopyN(io.Discard, res.Body, 1024)
+		res.Body.Close()
+	}
+
+	// Context canceled while reading the body
+	ctx, cancel = context.WithCancel(context.Background())
+
+	res, e = c.Do(NewRequestWithContext(ctx, MethodGet, url+"/slow"))
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	cancel()
+	io.ReadAll(res.Body)
+	res.Body.Close()
+
+	// Client closed while a request is in flight
+	if inflight, e = c.Get(url + "/big"); e != nil {
+		t.Fatal(e)
+	}
+
+	io.Copy(io.Discard, inflight.Body)
+
+	if e = c.Close(); e != nil {
+		t.Fatal(e)
+	}
+
+	// Connection handle is returned to the pool after it was shut down
+	inflight.Body.Close()
+
+	if n := LiveHandles() - before; n != 0 {
+		t.Fatalf("got %d live handles, expected 0", n)
+	}
+}
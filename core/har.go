@@ -0,0 +1,1006 @@
+package core
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mjwhitta/win"
+	"github.com/mjwhitta/win/errors"
+)
+
+// HAR is the root of a HAR 1.2 file.
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARCache is the cache info of a HAR entry, which is always empty.
+type HARCache struct{}
+
+// HARContent is the body of a HAR response.
+type HARContent struct {
+	Encoding string `json:"encoding,omitempty"`
+	MimeType string `json:"mimeType"`
+	Size     int64  `json:"size"`
+	Text     string `json:"text,omitempty"`
+}
+
+// HARCookie is a cookie in a HAR request or response.
+type HARCookie struct {
+	Domain   string `json:"domain,omitempty"`
+	Expires  string `json:"expires,omitempty"`
+	HTTPOnly bool   `json:"httpOnly,omitempty"`
+	Name     string `json:"name"`
+	Path     string `json:"path,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+	Value    string `json:"value"`
+}
+
+// HARCreator describes the application that created a HAR file.
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry is a single round trip in a HAR file.
+type HAREntry struct {
+	Cache           HARCache    `json:"cache"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Timings         HARTimings  `json:"timings"`
+}
+
+// HARLog is the log of a HAR file.
+type HARLog struct {
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+	Version string     `json:"version"`
+}
+
+// HARNameValue is a header, query parameter, or form parameter in a
+// HAR request or response.
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData is the body of a HAR request.
+type HARPostData struct {
+	MimeType string         `json:"mimeType"`
+	Params   []HARNameValue `json:"params,omitempty"`
+	Text     string         `json:"text,omitempty"`
+}
+
+// HARRecorder records the traffic of a Client in HAR 1.2 format. It
+// is attached as a Middleware, so each redirect hop and retry
+// attempt is a separate entry:
+//
+//	var rec = core.NewHARRecorder()
+//
+//	c.Middleware = append(c.Middleware, rec.Middleware)
+//	...
+//	rec.Save("traffic.har")
+//
+// Response bodies are recorded as they are read, so entries are only
+// complete once their Response Body is closed. Timings other than
+// wait and receive are only available for the Go standard library
+// backend. Redaction is applied when the HAR is exported.
+type HARRecorder struct {
+	// MaxBodySize is the maximum number of bytes of each request and
+	// response body to record. If 0, DefaultHARMaxBodySize is used.
+	// If negative, bodies are not recorded.
+	MaxBodySize int64
+
+	// Redact, if not nil, is called for every exported entry after
+	// RedactHeaders and RedactFields are applied, allowing for
+	// custom redaction.
+	Redact func(entry *HAREntry)
+
+	// RedactFields are the names of fields masked in JSON and form
+	// bodies, as well as query strings.
+	RedactFields []string
+
+	// RedactHeaders are the names of headers, whose values are
+	// masked. If a Cookie or Set-Cookie header is listed, the
+	// corresponding cookie values are masked as well.
+	RedactHeaders []string
+
+	entries []*harEntry
+	mutex   sync.Mutex
+}
+
+// HARRequest is the request of a HAR entry.
+type HARRequest struct {
+	BodySize    int64          `json:"bodySize"`
+	Cookies     []HARCookie    `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	HeadersSize int64          `json:"headersSize"`
+	HTTPVersion string         `json:"httpVersion"`
+	Method      string         `json:"method"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	QueryString []HARNameValue `json:"queryString"`
+	URL         string         `json:"url"`
+}
+
+// HARResponse is the response of a HAR entry. If the round trip
+// failed (e.g. the proxy refused the connection), Status is 0 and
+// Error is the reason.
+type HARResponse struct {
+	// BodySize is the size of the body as received, or -1 if it
+	// was decompressed or wasn't read to the end.
+	BodySize int64       `json:"bodySize"`
+	Content  HARContent  `json:"content"`
+	Cookies  []HARCookie `json:"cookies"`
+
+	// Error is a custom field w/ the error of a failed round trip.
+	Error string `json:"_error,omitempty"`
+
+	Headers     []HARNameValue `json:"headers"`
+	HeadersSize int64          `json:"headersSize"`
+	HTTPVersion string         `json:"httpVersion"`
+	RedirectURL string         `json:"redirectURL"`
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+}
+
+// HARTimings are the timings of a HAR entry, in milliseconds. A
+// value of -1 means the timing is not available.
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	Connect float64 `json:"connect"`
+	DNS     float64 `json:"dns"`
+	Receive float64 `json:"receive"`
+	Send    float64 `json:"send"`
+	SSL     float64 `json:"ssl"`
+	Wait    float64 `json:"wait"`
+}
+
+// harBody records a response body as it is read.
+type harBody struct {
+	body  io.ReadCloser
+	entry *harEntry
+	eof   bool
+	once  sync.Once
+	rec   *HARRecorder
+}
+
+// harBuffer is an io.Writer that keeps up to max bytes and counts
+// the rest.
+type harBuffer struct {
+	buf bytes.Buffer
+	max int64
+	n   int64
+}
+
+// harEntry is a HAREntry that is still being recorded. Once reqDone
+// is set, the request body is summarized and no longer recorded.
+type harEntry struct {
+	entry        HAREntry
+	reqBody      harBuffer
+	reqDone      bool
+	resBody      harBuffer
+	trace        *harTrace
+	uncompressed bool
+}
+
+// harReqBody records a request body as it is sent. The Backend may
+// still be reading it after the round trip returns (e.g. net/http
+// keeps writing when the server responds early), so it is recorded
+// under the recorder's mutex and summarized once it is done.
+type harReqBody struct {
+	body   io.Reader
+	entry  *harEntry
+	length int64
+	once   sync.Once
+	rec    *HARRecorder
+}
+
+// harTrace holds the timestamps from httptrace.
+type harTrace struct {
+	connectDone  time.Time
+	connectStart time.Time
+	dnsDone      time.Time
+	dnsStart     time.Time
+	done         time.Time
+	firstByte    time.Time
+	gotConn      time.Time
+	headers      time.Time
+	mutex        sync.Mutex
+	start        time.Time
+	tlsDone      time.Time
+	tlsStart     time.Time
+	wroteRequest time.Time
+}
+
+// DefaultHARMaxBodySize is the default maximum number of bytes of
+// each body recorded by a HARRecorder.
+const DefaultHARMaxBodySize int64 = 1 << 20
+
+// harRedacted replaces redacted values.
+const harRedacted string = "REDACTED"
+
+// NewHARRecorder will return a pointer to a new HARRecorder instance
+// that redacts the Authorization, Cookie, Proxy-Authorization, and
+// Set-Cookie headers by default.
+func NewHARRecorder() *HARRecorder {
+	return &HARRecorder{
+		RedactHeaders: []string{
+			"Authorization",
+			"Cookie",
+			"Proxy-Authorization",
+			"Set-Cookie",
+		},
+	}
+}
+
+func harCookies(cookies []*Cookie) []HARCookie {
+	var out []HARCookie = []HARCookie{}
+	var tmp HARCookie
+
+	for _, c := range cookies {
+		tmp = HARCookie{
+			Domain:   c.Domain,
+			HTTPOnly: c.HttpOnly,
+			Name:     c.Name,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			Value:    c.Value,
+		}
+
+		if !c.Expires.IsZero() {
+			tmp.Expires = c.Expires.UTC().Format(time.RFC3339)
+		}
+
+		out = append(out, tmp)
+	}
+
+	return out
+}
+
+// harHeaders will return the headers in a stable order.
+func harHeaders(hdrs Header) []HARNameValue {
+	var out []HARNameValue = []HARNameValue{}
+
+	for _, k := range hdrs.sortedKeys() {
+		for _, v := range hdrs[k] {
+			out = append(out, HARNameValue{Name: k, Value: v})
+		}
+	}
+
+	return out
+}
+
+// harMillis will return the milliseconds between two times, or -1 if
+// either is unknown.
+func harMillis(start time.Time, end time.Time) float64 {
+	if start.IsZero() || end.IsZero() || end.Before(start) {
+		return -1
+	}
+
+	return float64(end.Sub(start)) / float64(time.Millisecond)
+}
+
+func harQuery(rawURL string) []HARNameValue {
+	var out []HARNameValue = []HARNameValue{}
+	var uri *url.URL
+
+	if uri, _ = url.Parse(rawURL); uri == nil {
+		return out
+	}
+
+	for _, kv := range strings.Split(uri.RawQuery, "&") {
+		if kv == "" {
+			continue
+		}
+
+		k, v, _ := strings.Cut(kv, "=")
+		k, _ = url.QueryUnescape(k)
+		v, _ = url.QueryUnescape(v)
+		out = append(out, HARNameValue{Name: k, Value: v})
+	}
+
+	return out
+}
+
+// redactJSON will mask the named fields anywhere in a JSON value.
+func redactJSON(v any, fields map[string]bool) any {
+	switch v := v.(type) {
+	case []any:
+		for i := range v {
+			v[i] = redactJSON(v[i], fields)
+		}
+	case map[string]any:
+		for k := range v {
+			if fields[strings.ToLower(k)] {
+				v[k] = harRedacted
+			} else {
+				v[k] = redactJSON(v[k], fields)
+			}
+		}
+	}
+
+	return v
+}
+
+// redactText will mask the named fields in a JSON or form body. Other
+// bodies are returned unchanged.
+func redactText(
+	text string,
+	mimeType string,
+	fields map[string]bool,
+) string {
+	var b []byte
+	var e error
+	var mt string
+	var v any
+	var vals url.Values
+
+	if (text == "") || (len(fields) == 0) {
+		return text
+	}
+
+	mt, _, _ = mime.ParseMediaType(mimeType)
+
+	switch {
+	case (mt == "application/json") || strings.HasSuffix(mt, "+json"):
+		if e = json.Unmarshal([]byte(text), &v); e != nil {
+			return text
+		}
+
+		if b, e = json.Marshal(redactJSON(v, fields)); e != nil {
+			return text
+		}
+
+		return string(b)
+	case mt == "application/x-www-form-urlencoded":
+		if vals, e = url.ParseQuery(text); e != nil {
+			return text
+		}
+
+		for k := range vals {
+			if fields[strings.ToLower(k)] {
+				vals[k] = []string{harRedacted}
+			}
+		}
+
+		return vals.Encode()
+	}
+
+	return text
+}
+
+// redactURL will mask the named fields in the query string of a URL.
+func redactURL(rawURL string, fields map[string]bool) string {
+	var changed bool
+	var uri *url.URL
+	var vals url.Values
+
+	if (len(fields) == 0) || !strings.Contains(rawURL, "?") {
+		return rawURL
+	}
+
+	if uri, _ = url.Parse(rawURL); uri == nil {
+		return rawURL
+	}
+
+	vals = uri.Query()
+
+	for k := range vals {
+		if fields[strings.ToLower(k)] {
+			vals[k] = []string{harRedacted}
+			changed = true
+		}
+	}
+
+	if !changed {
+		return rawURL
+	}
+
+	uri.RawQuery = vals.Encode()
+
+	return uri.String()
+}
+
+// Close will finish recording the response body.
+func (b *harBody) Close() error {
+	var e error = b.body.Close()
+
+	b.finish()
+
+	return e
+}
+
+func (b *harBody) finish() {
+	b.once.Do(
+		func() {
+			b.rec.mutex.Lock()
+			defer b.rec.mutex.Unlock()
+
+			b.entry.finish(time.Now(), b.eof)
+		},
+	)
+}
+
+// Read will read from the response body, recording what is read.
+func (b *harBody) Read(p []byte) (int, error) {
+	var e error
+	var n int
+
+	n, e = b.body.Read(p)
+
+	if n > 0 {
+		b.rec.mutex.Lock()
+		b.entry.resBody.Write(p[:n])
+		b.rec.mutex.Unlock()
+	}
+
+	if e == io.EOF {
+		b.eof = true
+		b.finish()
+	}
+
+	return n, e
+}
+
+// Write will keep up to max bytes of p.
+func (b *harBuffer) Write(p []byte) (int, error) {
+	var keep int64 = b.max - int64(b.buf.Len())
+
+	if keep > int64(len(p)) {
+		keep = int64(len(p))
+	}
+
+	if keep > 0 {
+		b.buf.Write(p[:keep])
+	}
+
+	b.n += int64(len(p))
+
+	return len(p), nil
+}
+
+// Close will close the request body and finish recording it.
+func (b *harReqBody) Close() error {
+	var e error
+
+	if c, ok := b.body.(io.Closer); ok {
+		e = c.Close()
+	}
+
+	b.finish()
+
+	return e
+}
+
+func (b *harReqBody) finish() {
+	b.once.Do(
+		func() {
+			b.rec.mutex.Lock()
+			defer b.rec.mutex.Unlock()
+
+			b.entry.sentBody()
+		},
+	)
+}
+
+// Read will read from the request body, recording what is read. It
+// is done at EOF, on error, or once the expected length is read.
+func (b *harReqBody) Read(p []byte) (int, error) {
+	var done bool
+	var e error
+	var n int
+
+	n, e = b.body.Read(p)
+
+	b.rec.mutex.Lock()
+
+	if !b.entry.reqDone {
+		b.entry.reqBody.Write(p[:n])
+	}
+
+	done = (b.length >= 0) && (b.entry.reqBody.n >= b.length)
+
+	b.rec.mutex.Unlock()
+
+	if done || (e != nil) {
+		b.finish()
+	}
+
+	return n, e
+}
+
+// finish will fill in the response content and timings. The body
+// size is only known if the whole body was read w/o decompression.
+// The recorder's mutex must be held.
+func (e *harEntry) finish(done time.Time, eof bool) {
+	var res *HARResponse = &e.entry.Response
+	var t *harTrace = e.trace
+
+	res.BodySize = -1
+	if eof && !e.uncompressed {
+		res.BodySize = e.resBody.n
+	}
+
+	// The Backend is done w/ the request body by now, or whatever
+	// was sent so far is all that will be recorded
+	e.sentBody()
+
+	res.Content.Size = e.resBody.n
+	res.Content.Text, res.Content.Encoding = encodeBody(
+		e.resBody.buf.Bytes(),
+	)
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.done = done
+	e.entry.Timings = t.timings()
+	e.entry.Time = 0
+
+	for _, v := range []float64{
+		e.entry.Timings.Blocked,
+		e.entry.Timings.Connect,
+		e.entry.Timings.DNS,
+		e.entry.Timings.Receive,
+		e.entry.Timings.Send,
+		e.entry.Timings.Wait,
+	} {
+		if v > 0 {
+			e.entry.Time += v
+		}
+	}
+}
+
+// sentBody will fill in the request body once sent. The recorder's
+// mutex must be held.
+func (e *harEntry) sentBody() {
+	var mt string
+	var post *HARPostData = e.entry.Request.PostData
+	var vals url.Values
+
+	if e.reqDone {
+		return
+	}
+
+	e.reqDone = true
+	e.entry.Request.BodySize = e.reqBody.n
+
+	if post == nil {
+		return
+	}
+
+	// Binary bodies can't be represented
+	if utf8.Valid(e.reqBody.buf.Bytes()) {
+		post.Text = e.reqBody.buf.String()
+	}
+
+	mt, _, _ = mime.ParseMediaType(post.MimeType)
+	if mt == "application/x-www-form-urlencoded" {
+		vals, _ = url.ParseQuery(post.Text)
+
+		for _, k := range Header(vals).sortedKeys() {
+			for _, v := range vals[k] {
+				post.Params = append(
+					post.Params,
+					HARNameValue{Name: k, Value: v},
+				)
+			}
+		}
+	}
+}
+
+// wrote will fill in the request headers and cookies as they are
+// sent, including any cookies from the Jar and headers added by
+// inner Middleware or the Client.
+func (e *harEntry) wrote(r *Request) {
+	var pairs []string
+
+	e.entry.Request.Cookies = harCookies(r.Cookies())
+	e.entry.Request.Headers = harHeaders(r.Headers)
+
+	// Cookies are sent separately from the other headers
+	if len(r.Cookies()) > 0 {
+		for _, c := range r.Cookies() {
+			pairs = append(pairs, c.Name+"="+c.Value)
+		}
+
+		e.entry.Request.Headers = append(
+			e.entry.Request.Headers,
+			HARNameValue{Name: "Cookie", Value: strings.Join(pairs, "; ")},
+		)
+	}
+
+	if e.entry.Request.PostData != nil {
+		e.entry.Request.PostData.MimeType = r.Headers.Get("Content-Type")
+	}
+}
+
+// clientTrace will return an httptrace.ClientTrace that records the
+// timestamps of a round trip.
+func (t *harTrace) clientTrace() *httptrace.ClientTrace {
+	var set = func(field *time.Time) {
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+
+		if field.IsZero() {
+			*field = time.Now()
+		}
+	}
+
+	return &httptrace.ClientTrace{
+		ConnectDone: func(string, string, error) {
+			set(&t.connectDone)
+		},
+		ConnectStart: func(string, string) {
+			set(&t.connectStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			set(&t.dnsDone)
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			set(&t.dnsStart)
+		},
+		GotConn: func(httptrace.GotConnInfo) {
+			set(&t.gotConn)
+		},
+		GotFirstResponseByte: func() {
+			set(&t.firstByte)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			set(&t.tlsDone)
+		},
+		TLSHandshakeStart: func() {
+			set(&t.tlsStart)
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			set(&t.wroteRequest)
+		},
+	}
+}
+
+// timings will return the HARTimings. The mutex must be held.
+func (t *harTrace) timings() HARTimings {
+	var headers time.Time = t.firstByte
+	var timings HARTimings = HARTimings{
+		Blocked: -1,
+		Connect: harMillis(t.connectStart, t.connectDone),
+		DNS:     harMillis(t.dnsStart, t.dnsDone),
+		SSL:     harMillis(t.tlsStart, t.tlsDone),
+	}
+
+	if headers.IsZero() {
+		headers = t.headers
+	}
+
+	// Without httptrace, sending is included in waiting
+	if t.wroteRequest.IsZero() {
+		timings.Send = 0
+		timings.Wait = harMillis(t.start, headers)
+	} else {
+		timings.Blocked = harMillis(t.start, t.gotConn)
+		timings.Send = harMillis(t.gotConn, t.wroteRequest)
+		timings.Wait = harMillis(t.wroteRequest, headers)
+
+		// Connection setup is already reported separately
+		if timings.Blocked >= 0 {
+			for _, v := range []float64{timings.DNS, timings.Connect} {
+				if v > 0 {
+					timings.Blocked -= v
+				}
+			}
+
+			if timings.Blocked < 0 {
+				timings.Blocked = 0
+			}
+		}
+	}
+
+	timings.Receive = harMillis(headers, t.done)
+
+	// Required timings can't be -1
+	for _, v := range []*float64{
+		&timings.Receive,
+		&timings.Send,
+		&timings.Wait,
+	} {
+		if *v < 0 {
+			*v = 0
+		}
+	}
+
+	return timings
+}
+
+// failed will record an entry for a round trip that returned an
+// error, e.g. a transport or proxy failure.
+func (h *HARRecorder) failed(entry *harEntry, e error) {
+	entry.entry.Request.HTTPVersion = "HTTP/1.1"
+	entry.entry.Response = HARResponse{
+		Cookies:     []HARCookie{},
+		Error:       e.Error(),
+		Headers:     []HARNameValue{},
+		HeadersSize: -1,
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	entry.finish(time.Now(), false)
+	h.entries = append(h.entries, entry)
+}
+
+// HAR will return a redacted copy of everything recorded so far.
+func (h *HARRecorder) HAR() (*HAR, error) {
+	var b []byte
+	var e error
+	var out *HAR = &HAR{
+		Log: HARLog{
+			Creator: HARCreator{
+				Name:    "github.com/mjwhitta/win",
+				Version: win.Version,
+			},
+			Entries: []HAREntry{},
+			Version: "1.2",
+		},
+	}
+
+	h.mutex.Lock()
+
+	for _, entry := range h.entries {
+		out.Log.Entries = append(out.Log.Entries, entry.entry)
+	}
+
+	// Deep copy so redaction doesn't change the recorded entries
+	b, e = json.Marshal(out.Log.Entries)
+	h.mutex.Unlock()
+
+	if e != nil {
+		return nil, errors.Newf("failed to copy har entries: %w", e)
+	}
+
+	out.Log.Entries = nil
+	if e = json.Unmarshal(b, &out.Log.Entries); e != nil {
+		return nil, errors.Newf("failed to copy har entries: %w", e)
+	}
+
+	for i := range out.Log.Entries {
+		h.redact(&out.Log.Entries[i])
+	}
+
+	return out, nil
+}
+
+// Middleware will record each round trip, see HARRecorder.
+func (h *HARRecorder) Middleware(next RoundTripFunc) RoundTripFunc {
+	return func(r *Request) (*Response, error) {
+		var e error
+		var entry *harEntry = h.newEntry(r)
+		var res *Response
+
+		r = r.Clone(
+			withWroteRequest(
+				httptrace.WithClientTrace(
+					r.Context(),
+					entry.trace.clientTrace(),
+				),
+				entry.wrote,
+			),
+		)
+
+		// Record the request body as it is sent
+		if r.Body != nil {
+			r.Body = &harReqBody{
+				body:   r.Body,
+				entry:  entry,
+				length: r.outgoingLength(),
+				rec:    h,
+			}
+		}
+
+		res, e = next(r)
+
+		entry.trace.mutex.Lock()
+		entry.trace.headers = time.Now()
+		entry.trace.mutex.Unlock()
+
+		if e != nil {
+			h.failed(entry, e)
+			return nil, e
+		}
+
+		entry.uncompressed = res.Uncompressed
+		entry.entry.Request.HTTPVersion = res.Proto
+		entry.entry.Response = HARResponse{
+			BodySize: -1,
+			Content: HARContent{
+				MimeType: res.Header.Get("Content-Type"),
+			},
+			Cookies:     harCookies(res.Cookies()),
+			Headers:     harHeaders(res.Header),
+			HeadersSize: -1,
+			HTTPVersion: res.Proto,
+			RedirectURL: res.Header.Get("Location"),
+			Status:      res.StatusCode,
+			StatusText: strings.TrimSpace(
+				strings.TrimPrefix(res.Status, strconv.Itoa(res.StatusCode)),
+			),
+		}
+
+		if entry.entry.Request.HTTPVersion == "" {
+			entry.entry.Request.HTTPVersion = "HTTP/1.1"
+			entry.entry.Response.HTTPVersion = "HTTP/1.1"
+		}
+
+		h.mutex.Lock()
+		h.entries = append(h.entries, entry)
+		h.mutex.Unlock()
+
+		if res.Body == nil {
+			res.Body = io.NopCloser(bytes.NewReader(nil))
+		}
+
+		res.Body = &harBody{body: res.Body, entry: entry, rec: h}
+
+		return res, nil
+	}
+}
+
+func (h *HARRecorder) newEntry(r *Request) *harEntry {
+	var entry *harEntry = &harEntry{trace: &harTrace{start: time.Now()}}
+	var maxBody int64 = h.MaxBodySize
+
+	if maxBody == 0 {
+		maxBody = DefaultHARMaxBodySize
+	} else if maxBody < 0 {
+		maxBody = 0
+	}
+
+	entry.reqBody.max = maxBody
+	entry.resBody.max = maxBody
+
+	entry.entry = HAREntry{
+		Request: HARRequest{
+			BodySize:    0,
+			HeadersSize: -1,
+			Method:      r.Method,
+			QueryString: harQuery(r.URL),
+			URL:         r.URL,
+		},
+		StartedDateTime: entry.trace.start.Format(time.RFC3339Nano),
+	}
+
+	if r.Body != nil {
+		entry.entry.Request.PostData = &HARPostData{}
+	}
+
+	// Updated if the Request reaches the Backend
+	entry.wrote(r)
+
+	return entry
+}
+
+// redact will apply RedactHeaders, RedactFields, and Redact to the
+// entry.
+func (h *HARRecorder) redact(entry *HAREntry) {
+	var fields = map[string]bool{}
+	var hdrs = map[string]bool{}
+	var req *HARRequest = &entry.Request
+	var res *HARResponse = &entry.Response
+
+	for _, k := range h.RedactFields {
+		fields[strings.ToLower(k)] = true
+	}
+
+	for _, k := range h.RedactHeaders {
+		hdrs[strings.ToLower(k)] = true
+	}
+
+	for _, nvs := range [][]HARNameValue{req.Headers, res.Headers} {
+		for i := range nvs {
+			if hdrs[strings.ToLower(nvs[i].Name)] {
+				nvs[i].Value = harRedacted
+			}
+		}
+	}
+
+	for i := range req.QueryString {
+		if fields[strings.ToLower(req.QueryString[i].Name)] {
+			req.QueryString[i].Value = harRedacted
+		}
+	}
+
+	res.Error = strings.ReplaceAll(
+		res.Error,
+		req.URL,
+		redactURL(req.URL, fields),
+	)
+	req.URL = redactURL(req.URL, fields)
+	res.RedirectURL = redactURL(res.RedirectURL, fields)
+
+	if hdrs["cookie"] {
+		for i := range req.Cookies {
+			req.Cookies[i].Value = harRedacted
+		}
+	}
+
+	if hdrs["set-cookie"] {
+		for i := range res.Cookies {
+			res.Cookies[i].Value = harRedacted
+		}
+	}
+
+	if req.PostData != nil {
+		req.PostData.Text = redactText(
+			req.PostData.Text,
+			req.PostData.MimeType,
+			fields,
+		)
+
+		for i := range req.PostData.Params {
+			if fields[strings.ToLower(req.PostData.Params[i].Name)] {
+				req.PostData.Params[i].Value = harRedacted
+			}
+		}
+	}
+
+	if res.Content.Encoding == "" {
+		res.Content.Text = redactText(
+			res.Content.Text,
+			res.Content.MimeType,
+			fields,
+		)
+	}
+
+	if h.Redact != nil {
+		h.Redact(entry)
+	}
+}
+
+// Save will atomically write the HAR to the provided file.
+func (h *HARRecorder) Save(path string) error {
+	var buf bytes.Buffer
+	var e error
+
+	if _, e = h.WriteTo(&buf); e != nil {
+		return e
+	}
+
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+// WriteTo will write the HAR as JSON.
+func (h *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	var e error
+	var enc *json.Encoder = json.NewEncoder(&buf)
+	var har *HAR
+	var n int
+
+	if har, e = h.HAR(); e != nil {
+		return 0, e
+	}
+
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+
+	if e = enc.Encode(har); e != nil {
+		return 0, errors.Newf("failed to write har: %w", e)
+	}
+
+	if n, e = w.Write(buf.Bytes()); e != nil {
+		return int64(n), errors.Newf("failed to write har: %w", e)
+	}
+
+	return int64(n), nil
+}
@@ -0,0 +1,276 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newHARServer will return a server for the HARRecorder tests.
+func newHARServer() *httptest.Server {
+	return httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/reset":
+					conn, _, _ := w.(http.Hijacker).Hijack()
+					conn.Close()
+				case "/reject":
+					// Respond w/o reading the body
+					w.WriteHeader(http.StatusRequestEntityTooLarge)
+				default:
+					io.Copy(io.Discard, r.Body)
+					w.Write([]byte("hello"))
+				}
+			},
+		),
+	)
+}
+
+func TestHARRecorder(t *testing.T) {
+	var b Backend
+	var c *Client
+	var e error
+	var failed HARResponse
+	var har *HAR
+	var post *HARRequest
+	var rec *HARRecorder = NewHARRecorder()
+	var req *Request
+	var res *Response
+	var srv *httptest.Server = newHARServer()
+	var tests = []struct {
+		bodySize int64
+		read     bool
+		url      string
+	}{
+		{5, true, srv.URL + "/plain"},
+		{-1, false, srv.URL + "/plain"},
+	}
+	var uri *url.URL
+
+	defer srv.Close()
+
+	if b, e = NewStd("test", ""); e != nil {
+		t.Fatal(e)
+	}
+
+	rec.RedactFields = []string{"token"}
+
+	c = NewClient(b)
+	c.Jar = NewJar()
+	c.Middleware = []Middleware{
+		rec.Middleware,
+		func(next RoundTripFunc) RoundTripFunc {
+			return func(r *Request) (*Response, error) {
+				r = r.Clone(r.Context())
+				r.Headers.Set("X-Inner", "1")
+				return next(r)
+			}
+		},
+	}
+
+	uri, _ = url.Parse(srv.URL)
+	c.Jar.SetCookies(uri, []*http.Cookie{{Name: "sid", Value: "abc"}})
+
+	for _, test := range tests {
+		if res, e = c.Get(test.url); e != nil {
+			t.Fatal(e)
+		}
+
+		if test.read {
+			io.ReadAll(res.Body)
+		} else {
+			io.CopyN(io.Discard, res.Body, 1)
+		}
+
+		res.Body.Close()
+	}
+
+	// Form body of unknown length
+	req = NewRequestWithBody(
+		MethodPost,
+		srv.URL+"/form",
+		io.MultiReader(strings.NewReader("a=1&token=secret")),
+	)
+	req.Headers.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	if res, e = c.Do(req); e != nil {
+		t.Fatal(e)
+	}
+	res.Body.Close()
+
+	// Transport failures are recorded too
+	if _, e = c.Get(srv.URL + "/reset?token=secret"); e == nil {
+		t.Fatal("expected error")
+	}
+
+	if har, e = rec.HAR(); e != nil {
+		t.Fatal(e)
+	}
+
+	if len(har.Log.Entries) != len(tests)+2 {
+		t.Fatalf(
+			"got %d entries, expected %d",
+			len(har.Log.Entries),
+			len(tests)+2,
+		)
+	}
+
+	for i, test := range tests {
+		if n := har.Log.Entries[i].Response.BodySize; n != test.bodySize {
+			t.Errorf(
+				"%s: got bodySize %d, expected %d",
+				test.url,
+				n,
+				test.bodySize,
+			)
+		}
+	}
+
+	// Headers are recorded as sent, w/ cookies redacted
+	for k, expected := range map[string]string{
+		"Cookie":  harRedacted,
+		"X-Inner": "1",
+	} {
+		v := harValue(har.Log.Entries[0].Request.Headers, k)
+		if v != expected {
+			t.Errorf("got %s %q, expected %q", k, v, expected)
+		}
+	}
+
+	post = &har.Log.Entries[len(tests)].Request
+
+	if post.BodySize != 16 {
+		t.Errorf("got bodySize %d, expected 16", post.BodySize)
+	} else if post.PostData.Text != "a=1&token="+harRedacted {
+		t.Errorf("got postData %q", post.PostData.Text)
+	} else if len(post.PostData.Params) != 2 {
+		t.Errorf("got params %v", post.PostData.Params)
+	}
+
+	failed = har.Log.Entries[len(tests)+1].Response
+
+	if (failed.Status != 0) || (failed.Error == "") {
+		t.Errorf("got status %d and error %q", failed.Status, failed.Error)
+	}
+
+	if strings.Contains(failed.Error, "secret") {
+		t.Errorf("error not redacted: %s", failed.Error)
+	}
+
+	if failed.BodySize != -1 {
+		t.Errorf("got bodySize %d, expected -1", failed.BodySize)
+	}
+}
+
+// TestHAREarlyResponse checks that a request body that is still being
+// sent after the response arrives is recorded w/o a data race. Run w/
+// go test -race.
+func TestHAREarlyResponse(t *testing.T) {
+	var b Backend
+	var body []byte = bytes.Repeat([]byte("x"), 1<<20)
+	var c *Client
+	var e error
+	var har *HAR
+	var n int64
+	var rec *HARRecorder = NewHARRecorder()
+	var res *Response
+	var srv *httptest.Server = newHARServer()
+
+	defer srv.Close()
+
+	if b, e = NewStd("test", ""); e != nil {
+		t.Fatal(e)
+	}
+
+	c = NewClient(b)
+	c.Middleware = []Middleware{rec.Middleware}
+
+	for _, r := range []io.Reader{
+		bytes.NewReader(body),
+		io.MultiReader(bytes.NewReader(body)),
+	} {
+		res, e = c.Do(
+			NewRequestWithBody(MethodPost, srv.URL+"/reject", r),
+		)
+		if e != nil {
+			// The server may close the connection mid-upload
+			continue
+		}
+
+		// Export while the body may still be in flight
+		rec.HAR()
+
+		if res.StatusCode != http.StatusRequestEntityTooLarge {
+			t.Errorf("got status %d, expected 413", res.StatusCode)
+		}
+
+		res.Body.Close()
+	}
+
+	if har, e = rec.HAR(); e != nil {
+		t.Fatal(e)
+	}
+
+	for _, entry := range har.Log.Entries {
+		n = entry.Request.BodySize
+
+		if (n < 0) || (n > int64(len(body))) {
+			t.Errorf("got bodySize %d", n)
+		} else if int64(len(entry.Request.PostData.Text)) != n {
+			t.Errorf(
+				"got %d bytes of text, expected %d",
+				len(entry.Request.PostData.Text),
+				n,
+			)
+		}
+	}
+}
+
+func TestHARSave(t *testing.T) {
+	var b []byte
+	var e error
+	var files []os.DirEntry
+	var har HAR
+	var path string = filepath.Join(t.TempDir(), "traffic.har")
+	var rec *HARRecorder = NewHARRecorder()
+
+	if e = rec.Save(path); e != nil {
+		t.Fatal(e)
+	}
+
+	if b, e = os.ReadFile(path); e != nil {
+		t.Fatal(e)
+	}
+
+	if e = json.Unmarshal(b, &har); e != nil {
+		t.Fatal(e)
+	} else if har.Log.Version != "1.2" {
+		t.Errorf("got version %q, expected 1.2", har.Log.Version)
+	}
+
+	// No temp files are left behind
+	if files, e = os.ReadDir(filepath.Dir(path)); e != nil {
+		t.Fatal(e)
+	} else if len(files) != 1 {
+		t.Errorf("got files %v", files)
+	}
+}
+
+// harValue will return the first value for name.
+func harValue(nvs []HARNameValue, name string) string {
+	for _, nv := range nvs {
+		if nv.Name == name {
+			return nv.Value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,77 @@
+package core
+
+import (
+	"net/textproto"
+	"sort"
+)
+
+// Header represents the key-value pairs in an HTTP header. Keys are
+// canonicalized (e.g. "content-type" becomes "Content-Type") by all
+// methods, same as net/http.
+type Header map[string][]string
+
+// Add will add the key, value pair to the Header, appending to any
+// existing values associated with key.
+func (h Header) Add(key string, value string) {
+	key = textproto.CanonicalMIMEHeaderKey(key)
+	h[key] = append(h[key], value)
+}
+
+// Clone will return a deep copy of the Header, or nil if h is nil.
+func (h Header) Clone() Header {
+	var h2 Header
+
+	if h == nil {
+		return nil
+	}
+
+	h2 = make(Header, len(h))
+	for k, vs := range h {
+		h2[k] = append([]string(nil), vs...)
+	}
+
+	return h2
+}
+
+// Del will delete the values associated with key.
+func (h Header) Del(key string) {
+	delete(h, textproto.CanonicalMIMEHeaderKey(key))
+}
+
+// Get will return the first value associated with the given key, or
+// "" if there are none.
+func (h Header) Get(key string) string {
+	var vs []string = h.Values(key)
+
+	if len(vs) == 0 {
+		return ""
+	}
+
+	return vs[0]
+}
+
+// Set will set the Header entry associated with key to the single
+// value, replacing any existing values.
+func (h Header) Set(key string, value string) {
+	h[textproto.CanonicalMIMEHeaderKey(key)] = []string{value}
+}
+
+// sortedKeys will return the keys in a deterministic order, so
+// headers are always sent the same way.
+func (h Header) sortedKeys() []string {
+	var keys []string = make([]string, 0, len(h))
+
+	for k := range h {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Values will return all values associated with the given key. The
+// returned slice is not a copy.
+func (h Header) Values(key string) []string {
+	return h[textproto.CanonicalMIMEHeaderKey(key)]
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHeader(t *testing.T) {
+	var clone Header
+	var h Header = Header{}
+	var keys string
+
+	h.Add("content-type", "text/plain")
+	h.Add("X-MULTI", "a")
+	h.Add("x-multi", "b")
+	h.Set("x-single", "1")
+	h.Set("X-Single", "2")
+
+	for k, expected := range map[string]string{
+		"Content-Type": "text/plain",
+		"X-Multi":      "a,b",
+		"X-Single":     "2",
+	} {
+		if v := strings.Join(h[k], ","); v != expected {
+			t.Errorf("%s: got %q, expected %q", k, v, expected)
+		}
+	}
+
+	if v := h.Get("x-multi"); v != "a" {
+		t.Errorf("got %q, expected first value", v)
+	}
+
+	if v := h.Get("missing"); v != "" {
+		t.Errorf("got %q, expected empty string", v)
+	}
+
+	keys = strings.Join(h.sortedKeys(), ",")
+	if keys != "Content-Type,X-Multi,X-Single" {
+		t.Errorf("got keys %q, expected sorted", keys)
+	}
+
+	// Clones are deep
+	clone = h.Clone()
+	clone.Add("X-Multi", "c")
+	clone["X-Single"][0] = "3"
+
+	if (len(h.Values("X-Multi")) != 2) || (h.Get("X-Single") != "2") {
+		t.Error("Clone is not a deep copy")
+	}
+
+	h.Del("X-MULTI")
+
+	if h.Values("X-Multi") != nil {
+		t.Error("X-Multi was not deleted")
+	}
+
+	if Header(nil).Clone() != nil {
+		t.Error("expected nil Clone")
+	}
+}
+
+// TestHeaderWire checks that multiple values are sent and received
+// as separate header lines.
+func TestHeaderWire(t *testing.T) {
+	var b Backend
+	var c *Client
+	var e error
+	var req *Request
+	var res *Response
+	var srv *httptest.Server
+
+	srv = httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				var v string = strings.Join(r.Header.Values("X-Test"), ",")
+
+				if v != "a,b" {
+					t.Errorf("got X-Test %q, expected %q", v, "a,b")
+				}
+
+				w.Header().Add("X-Reply", "1")
+				w.Header().Add("X-Reply", "2")
+			},
+		),
+	)
+	defer srv.Close()
+
+	if b, e = NewStd("test", ""); e != nil {
+		t.Fatal(e)
+	}
+
+	c = NewClient(b)
+
+	req = NewRequest(MethodGet, srv.URL)
+	req.Headers.Add("x-test", "a")
+	req.Headers.Add("X-Test", "b")
+
+	if res, e = c.Do(req); e != nil {
+		t.Fatal(e)
+	}
+	res.Body.Close()
+
+	if v := strings.Join(res.Header.Values("x-reply"), ","); v != "1,2" {
+		t.Errorf("got X-Reply %q, expected %q", v, "1,2")
+	}
+}
@@ -0,0 +1,26 @@
+package core
+
+import "github.com/mjwhitta/win/errors"
+
+// ErrNoCookie is returned by Request's Cookie method when a cookie is
+// not found.
+var ErrNoCookie = errors.New("named cookie not present")
+
+// ErrUseLastResponse can be returned by Client.CheckRedirect to
+// control how redirects are processed. If returned, the next request
+// is not sent and the most recent response is returned with its body
+// unclosed.
+var ErrUseLastResponse = errors.New("use last response")
+
+// Common HTTP methods.
+const (
+	MethodConnect string = "CONNECT"
+	MethodDelete  string = "DELETE"
+	MethodGet     string = "GET"
+	MethodHead    string = "HEAD"
+	MethodOptions string = "OPTIONS"
+	MethodPatch   string = "PATCH"
+	MethodPost    string = "POST"
+	MethodPut     string = "PUT"
+	MethodTrace   string = "TRACE"
+)
@@ -0,0 +1,550 @@
+package core
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mjwhitta/win/errors"
+)
+
+// Jar is an in-memory implementation of net/http.CookieJar that
+// follows the storage model from RFC 6265 section 5.3. Cookies are
+// matched by domain, path, expiry, and the Secure flag. There is no
+// public suffix list, so Domain attributes w/o a dot are rejected
+// unless they match the host exactly. It is safe for concurrent use.
+type Jar struct {
+	entries map[string]*jarEntry
+	err     error
+	mutex   sync.Mutex
+	path    string
+	seq     uint64
+}
+
+// jarEntry is a cookie as stored in a Jar.
+type jarEntry struct {
+	Created    time.Time
+	Domain     string
+	Expires    time.Time
+	HostOnly   bool
+	HttpOnly   bool
+	Name       string
+	Path       string
+	Persistent bool
+	SameSite   SameSite
+	Secure     bool
+	Value      string
+
+	// seq breaks ties between entries created at the same time
+	seq uint64
+}
+
+// NewFileJar will return a pointer to a new Jar instance that is
+// backed by the provided file. Cookies are loaded from the file, if
+// it exists, and the file is saved atomically whenever the Jar
+// changes. Files ending in ".json" use the JSON format, anything else
+// uses the Netscape cookies.txt format. Same as curl, expired cookies
+// are dropped and session cookies are kept.
+func NewFileJar(path string) (*Jar, error) {
+	var b []byte
+	var cookies []*Cookie
+	var e error
+	var j *Jar = NewJar()
+
+	j.path = path
+
+	if b, e = os.ReadFile(path); e != nil {
+		if os.IsNotExist(e) {
+			return j, nil
+		}
+
+		return nil, errors.Newf("failed to read %s: %w", path, e)
+	}
+
+	if isJSONPath(path) {
+		cookies, e = ReadJSONCookies(bytes.NewReader(b))
+	} else {
+		cookies, e = ReadNetscapeCookies(bytes.NewReader(b))
+	}
+
+	if e != nil {
+		return nil, errors.Newf("failed to load %s: %w", path, e)
+	}
+
+	j.importCookies(cookies, time.Now())
+
+	return j, nil
+}
+
+// NewJar will return a pointer to a new, empty Jar instance.
+func NewJar() *Jar {
+	return &Jar{entries: map[string]*jarEntry{}}
+}
+
+func canonicalHost(u *url.URL) string {
+	return strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+}
+
+// defaultPath will return the default-path of a URL path as defined
+// in RFC 6265 section 5.1.4.
+func defaultPath(path string) string {
+	var i int
+
+	if !strings.HasPrefix(path, "/") {
+		return "/"
+	}
+
+	if i = strings.LastIndex(path, "/"); i == 0 {
+		return "/"
+	}
+
+	return path[:i]
+}
+
+// domainMatch will return true if host domain-matches domain as
+// defined in RFC 6265 section 5.1.3.
+func domainMatch(host string, domain string) bool {
+	if host == domain {
+		return true
+	}
+
+	if net.ParseIP(host) != nil {
+		return false
+	}
+
+	return strings.HasSuffix(host, "."+domain)
+}
+
+func isJSONPath(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
+
+// newJarEntry will return the jarEntry for a cookie received from the
+// provided URL, or nil if the cookie should be rejected.
+func newJarEntry(
+	u *url.URL,
+	c *http.Cookie,
+	now time.Time,
+) *jarEntry {
+	var entry *jarEntry = &jarEntry{
+		Created:  now,
+		HttpOnly: c.HttpOnly,
+		Name:     c.Name,
+		Path:     c.Path,
+		SameSite: SameSite(c.SameSite),
+		Secure:   c.Secure,
+		Value:    c.Value,
+	}
+	var host string = canonicalHost(u)
+
+	// Secure cookies can only be set over https
+	if c.Secure && (u.Scheme != "https") {
+		return nil
+	}
+
+	entry.Domain = strings.ToLower(strings.TrimPrefix(c.Domain, "."))
+
+	switch {
+	case entry.Domain == "":
+		entry.Domain = host
+		entry.HostOnly = true
+	case !domainMatch(host, entry.Domain):
+		return nil
+	case !strings.Contains(entry.Domain, ".") && (entry.Domain != host):
+		// Likely a TLD
+		return nil
+	}
+
+	if !strings.HasPrefix(entry.Path, "/") {
+		entry.Path = defaultPath(u.EscapedPath())
+	}
+
+	entry.setExpiry(c.MaxAge, c.Expires, now)
+
+	return entry
+}
+
+// pathMatch will return true if path path-matches cookiePath as
+// defined in RFC 6265 section 5.1.4.
+func pathMatch(path string, cookiePath string) bool {
+	switch {
+	case path == cookiePath:
+		return true
+	case !strings.HasPrefix(path, cookiePath):
+		return false
+	case strings.HasSuffix(cookiePath, "/"):
+		return true
+	}
+
+	return path[len(cookiePath)] == '/'
+}
+
+// toStdCookie will convert a Cookie to a net/http.Cookie for use with
+// a net/http.CookieJar.
+func toStdCookie(c *Cookie) *http.Cookie {
+	return &http.Cookie{
+		Domain:   c.Domain,
+		Expires:  c.Expires,
+		HttpOnly: c.HttpOnly,
+		MaxAge:   c.MaxAge,
+		Name:     c.Name,
+		Path:     c.Path,
+		Raw:      c.Raw,
+		SameSite: http.SameSite(c.SameSite),
+		Secure:   c.Secure,
+		Value:    c.Value,
+	}
+}
+
+// withJarCookies will return a shallow copy of the Request with the
+// provided cookies added. Cookies explicitly added to the Request take
+// precedence over those with the same name from the Jar.
+func withJarCookies(r *Request, cookies []*http.Cookie) *Request {
+	var out *Request
+	var seen = map[string]bool{}
+
+	if len(cookies) == 0 {
+		return r
+	}
+
+	out = new(Request)
+	*out = *r
+	out.cookies = append([]*Cookie(nil), r.cookies...)
+
+	for _, c := range r.cookies {
+		seen[c.Name] = true
+	}
+
+	// Cookies are unique by name in a Request, so keep the first
+	// (most specific) cookie from the Jar
+	for _, c := range cookies {
+		if !seen[c.Name] {
+			out.AddCookie(&Cookie{Name: c.Name, Value: c.Value})
+			seen[c.Name] = true
+		}
+	}
+
+	return out
+}
+
+// autoSave will save a file-backed Jar, recording any error for Err.
+func (j *Jar) autoSave(now time.Time) {
+	if j.path != "" {
+		j.err = j.save(now)
+	}
+}
+
+// Cookies will return the cookies to send in a request for the
+// provided URL, ordered by longest path first, then by creation
+// time. Expired cookies are removed from the Jar.
+func (j *Jar) Cookies(u *url.URL) []*http.Cookie {
+	var cookies []*http.Cookie
+	var host string
+	var matches []*jarEntry
+	var now time.Time = time.Now()
+	var path string
+
+	if (u.Scheme != "http") && (u.Scheme != "https") {
+		return nil
+	}
+
+	host = canonicalHost(u)
+
+	if path = u.EscapedPath(); path == "" {
+		path = "/"
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	for k, entry := range j.entries {
+		switch {
+		case entry.expired(now):
+			delete(j.entries, k)
+		case entry.HostOnly && (host != entry.Domain):
+		case !entry.HostOnly && !domainMatch(host, entry.Domain):
+		case !pathMatch(path, entry.Path):
+		case entry.Secure && (u.Scheme != "https"):
+		default:
+			matches = append(matches, entry)
+		}
+	}
+
+	sort.Slice(
+		matches,
+		func(i int, k int) bool {
+			if len(matches[i].Path) != len(matches[k].Path) {
+				return len(matches[i].Path) > len(matches[k].Path)
+			}
+
+			return matches[i].seq < matches[k].seq
+		},
+	)
+
+	for _, entry := range matches {
+		cookies = append(
+			cookies,
+			&http.Cookie{Name: entry.Name, Value: entry.Value},
+		)
+	}
+
+	return cookies
+}
+
+// Err will return the error from the most recent automatic save of a
+// file-backed Jar, if any.
+func (j *Jar) Err() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return j.err
+}
+
+// Export will return a copy of every unexpired cookie in the Jar,
+// e.g. for use with WriteJSONCookies or WriteNetscapeCookies.
+func (j *Jar) Export() []*Cookie {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	return j.exportCookies(time.Now())
+}
+
+func (j *Jar) exportCookies(now time.Time) []*Cookie {
+	var cookies []*Cookie
+	var entries []*jarEntry
+
+	for _, entry := range j.entries {
+		if !entry.expired(now) {
+			entries = append(entries, entry)
+		}
+	}
+
+	// Keep the output stable
+	sort.Slice(
+		entries,
+		func(i int, k int) bool {
+			return entries[i].seq < entries[k].seq
+		},
+	)
+
+	for _, entry := range entries {
+		cookies = append(cookies, entry.cookie())
+	}
+
+	return cookies
+}
+
+// Import will add the provided cookies to the Jar, e.g. from
+// ReadJSONCookies or ReadNetscapeCookies. Cookies w/o a Domain are
+// skipped and expired cookies delete any matching cookie from the
+// Jar. A file-backed Jar is saved afterwards.
+func (j *Jar) Import(cookies ...*Cookie) {
+	var now time.Time = time.Now()
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.importCookies(cookies, now) {
+		j.autoSave(now)
+	}
+}
+
+func (j *Jar) importCookies(cookies []*Cookie, now time.Time) bool {
+	var changed bool
+	var entry *jarEntry
+
+	for _, c := range cookies {
+		if (c == nil) || (c.Name == "") || (c.Domain == "") {
+			continue
+		}
+
+		entry = &jarEntry{
+			Created:  now,
+			Domain:   strings.ToLower(strings.TrimPrefix(c.Domain, ".")),
+			HostOnly: c.HostOnly,
+			HttpOnly: c.HttpOnly,
+			Name:     c.Name,
+			Path:     c.Path,
+			SameSite: c.SameSite,
+			Secure:   c.Secure,
+			Value:    c.Value,
+		}
+
+		if !strings.HasPrefix(entry.Path, "/") {
+			entry.Path = "/"
+		}
+
+		entry.setExpiry(c.MaxAge, c.Expires, now)
+
+		if j.store(entry, now) {
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+// Save will atomically write the cookies in a file-backed Jar to its
+// file. Same as curl, expired cookies are skipped and session cookies
+// are written with an expiry of 0. It is a no-op for in-memory Jars.
+func (j *Jar) Save() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.path == "" {
+		return nil
+	}
+
+	j.err = j.save(time.Now())
+	return j.err
+}
+
+func (j *Jar) save(now time.Time) error {
+	var buf bytes.Buffer
+	var cookies []*Cookie = j.exportCookies(now)
+	var e error
+
+	if isJSONPath(j.path) {
+		e = WriteJSONCookies(&buf, cookies)
+	} else {
+		e = WriteNetscapeCookies(&buf, cookies)
+	}
+
+	if e != nil {
+		return e
+	}
+
+	return writeFileAtomic(j.path, buf.Bytes())
+}
+
+// SetCookies will store the cookies received in a response from the
+// provided URL. Cookies that are already expired delete any matching
+// cookie from the Jar. A file-backed Jar is saved afterwards, if
+// anything changed.
+func (j *Jar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	var changed bool
+	var entry *jarEntry
+	var now time.Time = time.Now()
+
+	if (u.Scheme != "http") && (u.Scheme != "https") {
+		return
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	for _, c := range cookies {
+		if (c == nil) || (c.Name == "") {
+			continue
+		}
+
+		if entry = newJarEntry(u, c, now); entry == nil {
+			continue
+		}
+
+		if j.store(entry, now) {
+			changed = true
+		}
+	}
+
+	if changed {
+		j.autoSave(now)
+	}
+}
+
+// store will add or replace the entry, or delete the matching entry if
+// it is expired. It returns true if the Jar changed.
+func (j *Jar) store(entry *jarEntry, now time.Time) bool {
+	var key string = entry.key()
+	var old *jarEntry
+	var ok bool
+
+	if j.entries == nil {
+		j.entries = map[string]*jarEntry{}
+	}
+
+	old, ok = j.entries[key]
+
+	if entry.expired(now) {
+		delete(j.entries, key)
+		return ok
+	}
+
+	// Keep the creation time of the cookie being replaced
+	if ok {
+		entry.Created = old.Created
+		entry.seq = old.seq
+
+		// Same expiry, even if the monotonic clock or location
+		// differ, so == can compare the rest
+		if old.Expires.Equal(entry.Expires) {
+			entry.Expires = old.Expires
+		}
+
+		if *old == *entry {
+			return false
+		}
+	} else {
+		j.seq++
+		entry.seq = j.seq
+	}
+
+	j.entries[key] = entry
+
+	return true
+}
+
+func (entry *jarEntry) cookie() *Cookie {
+	var c *Cookie = &Cookie{
+		Domain:   entry.Domain,
+		HostOnly: entry.HostOnly,
+		HttpOnly: entry.HttpOnly,
+		Name:     entry.Name,
+		Path:     entry.Path,
+		SameSite: entry.SameSite,
+		Secure:   entry.Secure,
+		Value:    entry.Value,
+	}
+
+	if entry.Persistent {
+		c.Expires = entry.Expires
+	}
+
+	return c
+}
+
+func (entry *jarEntry) expired(now time.Time) bool {
+	return entry.Persistent && !entry.Expires.After(now)
+}
+
+func (entry *jarEntry) key() string {
+	return entry.Domain + ";" + entry.Path + ";" + entry.Name
+}
+
+// setExpiry will set the expiry from the Max-Age and Expires
+// attributes. Max-Age takes precedence over Expires and if neither
+// is set, it is a session cookie.
+func (entry *jarEntry) setExpiry(
+	maxAge int,
+	expires time.Time,
+	now time.Time,
+) {
+	switch {
+	case maxAge < 0:
+		entry.Expires = time.Unix(0, 0)
+		entry.Persistent = true
+	case maxAge > 0:
+		entry.Expires = now.Add(time.Duration(maxAge) * time.Second)
+		entry.Persistent = true
+	case !expires.IsZero():
+		entry.Expires = expires
+		entry.Persistent = true
+	}
+}
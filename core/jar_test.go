@@ -0,0 +1,254 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// jarNames will return the names of the cookies the Jar would send
+// to the provided URL, in order.
+func jarNames(j *Jar, uri string) string {
+	var names []string
+	var u *url.URL
+
+	u, _ = url.Parse(uri)
+
+	for _, c := range j.Cookies(u) {
+		names = append(names, c.Name)
+	}
+
+	return strings.Join(names, ",")
+}
+
+func TestJar(t *testing.T) {
+	var j *Jar = NewJar()
+	var names string
+	var u *url.URL
+
+	u, _ = url.Parse("https://www.example.com/a/b")
+
+	j.SetCookies(
+		u,
+		[]*http.Cookie{
+			{Name: "host", Value: "1"},
+			{Name: "domain", Value: "1", Domain: ".Example.com"},
+			{Name: "root", Value: "1", Path: "/"},
+			{Name: "secure", Value: "1", Path: "/", Secure: true},
+			{Name: "long", Value: "1", Path: "/a/b/c"},
+			{Name: "tld", Value: "1", Domain: "com"},
+			{Name: "other", Value: "1", Domain: "other.com"},
+			{Name: "", Value: "1"},
+			nil,
+		},
+	)
+
+	for uri, expected := range map[string]string{
+		"https://www.example.com/a/b/c": "long,host,domain,root,secure",
+		"https://www.example.com/a":     "host,domain,root,secure",
+		"http://www.example.com/":       "root",
+		"https://example.com/a/":        "domain",
+		"https://sub.www.example.com/a": "domain",
+		"https://other.com/":            "",
+		"ftp://www.example.com/a":       "",
+	} {
+		if names = jarNames(j, uri); names != expected {
+			t.Errorf("%s: got %q, expected %q", uri, names, expected)
+		}
+	}
+
+	// Secure cookies can't be set over http
+	u, _ = url.Parse("http://www.example.com/")
+	j.SetCookies(u, []*http.Cookie{{Name: "s", Value: "1", Secure: true}})
+
+	if names = jarNames(j, "http://www.example.com/"); names != "root" {
+		t.Errorf("got %q, expected %q", names, "root")
+	}
+
+	// Expired cookies delete existing ones, but keep the order
+	u, _ = url.Parse("https://www.example.com/a/b")
+	j.SetCookies(
+		u,
+		[]*http.Cookie{
+			{Name: "root", Value: "1", Path: "/", MaxAge: -1},
+			{Name: "host", Value: "2"},
+		},
+	)
+
+	names = jarNames(j, "https://www.example.com/a")
+	if names != "host,domain,secure" {
+		t.Errorf("got %q, expected %q", names, "host,domain,secure")
+	}
+}
+
+func TestFileJar(t *testing.T) {
+	var dir string
+	var e error
+	var files []os.DirEntry
+	var j *Jar
+	var names string
+	var u *url.URL
+
+	u, _ = url.Parse("https://example.com/")
+
+	for _, name := range []string{"cookies.txt", "cookies.json"} {
+		dir = t.TempDir()
+
+		if j, e = NewFileJar(filepath.Join(dir, name)); e != nil {
+			t.Fatal(e)
+		}
+
+		j.SetCookies(
+			u,
+			[]*http.Cookie{
+				{Name: "session", Value: "1"},
+				{Name: "persistent", Value: "2", MaxAge: 3600},
+				{Name: "http", Value: "3", HttpOnly: true, Path: "/"},
+				{Name: "expired", Value: "4", MaxAge: -1},
+			},
+		)
+
+		if e = j.Err(); e != nil {
+			t.Fatal(e)
+		}
+
+		// No temp files are left behind
+		if files, e = os.ReadDir(dir); e != nil {
+			t.Fatal(e)
+		} else if (len(files) != 1) || (files[0].Name() != name) {
+			t.Errorf("%s: got files %v", name, files)
+		}
+
+		// Reload
+		if j, e = NewFileJar(filepath.Join(dir, name)); e != nil {
+			t.Fatal(e)
+		}
+
+		names = jarNames(j, u.String())
+		if names != "session,persistent,http" {
+			t.Errorf("%s: got %q", name, names)
+		}
+	}
+
+	// Malformed JSON is an error
+	os.WriteFile(filepath.Join(dir, "bad.json"), []byte("["), 0o600)
+
+	if _, e = NewFileJar(filepath.Join(dir, "bad.json")); e == nil {
+		t.Error("expected error for malformed JSON")
+	}
+}
+
+// TestJarStore checks that storing an identical cookie isn't
+// reported as a change, regardless of the monotonic clock.
+func TestJarStore(t *testing.T) {
+	var entry jarEntry = jarEntry{
+		Domain:     "example.com",
+		Expires:    time.Now().Add(time.Hour),
+		Name:       "a",
+		Path:       "/",
+		Persistent: true,
+		Value:      "b",
+	}
+	var j *Jar = NewJar()
+	var same jarEntry = entry
+
+	same.Expires = entry.Expires.Round(0).UTC()
+
+	if !j.store(&entry, time.Now()) {
+		t.Error("new cookie was not stored")
+	}
+
+	if j.store(&same, time.Now()) {
+		t.Error("identical cookie reported as a change")
+	}
+
+	same.Value = "c"
+
+	if !j.store(&same, time.Now()) {
+		t.Error("changed cookie was not stored")
+	}
+}
+
+// TestJarResponseCookies checks that cookies sent from the Jar are
+// not reported as received cookies.
+func TestJarResponseCookies(t *testing.T) {
+	var b Backend
+	var c *Client
+	var e error
+	var req *http.Request
+	var res *Response
+	var srv *httptest.Server
+	var stdRes *http.Response
+	var uri *url.URL
+
+	srv = httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				// Exactly one Cookie header, w/ the Jar's cookies
+				if v := r.Header.Values("Cookie"); len(v) != 1 {
+					t.Errorf("got Cookie %v, expected 1 header", v)
+				}
+
+				if _, e := r.Cookie("sid"); e != nil {
+					t.Errorf("cookie not sent: %s", e)
+				}
+
+				if r.URL.Path == "/login" {
+					http.SetCookie(w, &http.Cookie{Name: "new", Value: "1"})
+				}
+			},
+		),
+	)
+	defer srv.Close()
+
+	if b, e = NewStd("test", ""); e != nil {
+		t.Fatal(e)
+	}
+
+	c = NewClient(b)
+	c.Jar = NewJar()
+
+	uri, _ = url.Parse(srv.URL)
+	c.Jar.SetCookies(uri, []*http.Cookie{{Name: "sid", Value: "abc"}})
+
+	if res, e = c.Get(srv.URL); e != nil {
+		t.Fatal(e)
+	}
+	res.Body.Close()
+
+	if n := len(res.Cookies()); n != 0 {
+		t.Errorf("got %d cookies, expected 0", n)
+	}
+
+	// Only the cookie that was set
+	if res, e = c.Get(srv.URL + "/login"); e != nil {
+		t.Fatal(e)
+	}
+	res.Body.Close()
+
+	if (len(res.Cookies()) != 1) || (res.Cookies()[0].Name != "new") {
+		t.Errorf("got cookies %v, expected [new=1]", res.Cookies())
+	}
+
+	// Received cookies are stored in the Jar
+	if names := jarNames(c.Jar.(*Jar), srv.URL); names != "sid,new" {
+		t.Errorf("got %q, expected %q", names, "sid,new")
+	}
+
+	// No fake Set-Cookie headers via Transport
+	req, _ = http.NewRequest(http.MethodGet, srv.URL, nil)
+
+	if stdRes, e = (&Transport{Client: c}).RoundTrip(req); e != nil {
+		t.Fatal(e)
+	}
+	stdRes.Body.Close()
+
+	if v := stdRes.Header.Values("Set-Cookie"); len(v) != 0 {
+		t.Errorf("got Set-Cookie %v, expected none", v)
+	}
+}
@@ -0,0 +1,141 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	goerrors "errors"
+	"io"
+
+	"github.com/mjwhitta/win/errors"
+)
+
+// MaxErrorBodySize is the maximum number of bytes of a non-2xx
+// response body that are kept in a StatusError.
+const MaxErrorBodySize int64 = 1 << 20
+
+// StatusError is returned by the JSON helpers (e.g. Client.GetJSON)
+// when the server responds w/ a non-2xx status code. Use errors.As to
+// inspect it:
+//
+//	var se *core.StatusError
+//
+//	if e = c.GetJSON(url, &out); errors.As(e, &se) {
+//		fmt.Println(se.StatusCode, se.JSON)
+//	}
+type StatusError struct {
+	// Body is the raw response body, up to MaxErrorBodySize bytes.
+	Body []byte
+
+	Header Header
+
+	// JSON is the decoded response body, or nil if the body is not
+	// valid JSON. See Decode to decode into a specific type.
+	JSON any
+
+	Status     string
+	StatusCode int
+}
+
+func newStatusError(res *Response) *StatusError {
+	var se *StatusError = &StatusError{
+		Header:     res.Header,
+		Status:     res.Status,
+		StatusCode: res.StatusCode,
+	}
+
+	// Errors are ignored, the body is only informational
+	se.Body, _ = io.ReadAll(io.LimitReader(res.Body, MaxErrorBodySize))
+
+	if json.Valid(se.Body) {
+		json.Unmarshal(se.Body, &se.JSON)
+	}
+
+	return se
+}
+
+// DoJSON will send the HTTP request w/ in (if not nil) marshaled as
+// the JSON body, and decode a 2xx response body into out (if not
+// nil). The Content-Type and Accept headers are set, unless already
+// present. Non-2xx responses return a *StatusError. The response body
+// is decoded as it is streamed and is always closed.
+func (c *Client) DoJSON(r *Request, in any, out any) error {
+	var b []byte
+	var e error
+	var res *Response
+
+	// Avoid modifying the caller's Request
+	r = r.Clone(r.Context())
+
+	if in != nil {
+		if b, e = json.Marshal(in); e != nil {
+			return errors.Newf("failed to marshal json: %w", e)
+		}
+
+		r.Body = bytes.NewReader(b)
+		r.ContentLength = int64(len(b))
+		r.GetBody = func() (io.Reader, error) {
+			return bytes.NewReader(b), nil
+		}
+
+		if r.Headers.Get("Content-Type") == "" {
+			r.Headers.Set("Content-Type", "application/json")
+		}
+	}
+
+	if r.Headers.Get("Accept") == "" {
+		r.Headers.Set("Accept", "application/json")
+	}
+
+	if res, e = c.Do(r); e != nil {
+		return e
+	}
+	defer res.Body.Close()
+
+	if (res.StatusCode < 200) || (res.StatusCode > 299) {
+		return newStatusError(res)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	// Empty body (e.g. 204) leaves out unchanged
+	e = json.NewDecoder(res.Body).Decode(out)
+	if (e != nil) && !goerrors.Is(e, io.EOF) {
+		return errors.Newf("failed to decode json: %w", e)
+	}
+
+	return nil
+}
+
+// GetJSON will make a GET request and decode the JSON response body
+// into out, see DoJSON.
+func (c *Client) GetJSON(url string, out any) error {
+	return c.DoJSON(NewRequest(MethodGet, url), nil, out)
+}
+
+// PostJSON will make a POST request w/ in as the JSON body and decode
+// the JSON response body into out, see DoJSON.
+func (c *Client) PostJSON(url string, in any, out any) error {
+	return c.DoJSON(NewRequest(MethodPost, url), in, out)
+}
+
+// PutJSON will make a PUT request w/ in as the JSON body and decode
+// the JSON response body into out, see DoJSON.
+func (c *Client) PutJSON(url string, in any, out any) error {
+	return c.DoJSON(NewRequest(MethodPut, url), in, out)
+}
+
+// Decode will unmarshal the raw response body into v.
+func (e *StatusError) Decode(v any) error {
+	if err := json.Unmarshal(e.Body, v); err != nil {
+		return errors.Newf("failed to decode json: %w", err)
+	}
+
+	return nil
+}
+
+// Error will return a string representation of the StatusError.
+func (e *StatusError) Error() string {
+	return "unexpected status " + e.Status
+}
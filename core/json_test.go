@@ -0,0 +1,205 @@
+package core
+
+import (
+	"encoding/json"
+	goerrors "errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/mjwhitta/win/errors"
+)
+
+// newJSONFake will return a Fake w/ an echo endpoint and endpoints
+// for each kind of response.
+func newJSONFake() *Fake {
+	var f *Fake = NewFake()
+
+	f.HandleFunc(
+		"/echo",
+		func(w http.ResponseWriter, r *http.Request) {
+			var b []byte
+			var out map[string]any = map[string]any{}
+
+			b, _ = io.ReadAll(r.Body)
+			json.Unmarshal(b, &out)
+
+			out["accept"] = r.Header.Get("Accept")
+			out["contentType"] = r.Header.Get("Content-Type")
+			out["method"] = r.Method
+
+			json.NewEncoder(w).Encode(out)
+		},
+	)
+	f.HandleFunc(
+		"/empty",
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		},
+	)
+	f.HandleFunc(
+		"/error",
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Test", "a")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":42,"message":"bad"}`))
+		},
+	)
+	f.HandleFunc(
+		"/html",
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("<html>" + strings.Repeat("x", 100) + "</html>"))
+		},
+	)
+	f.HandleFunc(
+		"/invalid",
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("{"))
+		},
+	)
+
+	return f
+}
+
+func TestJSON(t *testing.T) {
+	var c *Client = NewClient(newJSONFake())
+	var e error
+	var out map[string]any
+	var r *Request
+
+	// Methods and default headers
+	for method, do := range map[string]func() error{
+		MethodGet: func() error {
+			return c.GetJSON("http://example.com/echo", &out)
+		},
+		MethodPost: func() error {
+			return c.PostJSON(
+				"http://example.com/echo",
+				map[string]int{"a": 1},
+				&out,
+			)
+		},
+		MethodPut: func() error {
+			return c.PutJSON(
+				"http://example.com/echo",
+				map[string]int{"a": 1},
+				&out,
+			)
+		},
+	} {
+		out = nil
+
+		if e = do(); e != nil {
+			t.Fatal(e)
+		}
+
+		if (out["method"] != method) || (out["accept"] != "application/json") {
+			t.Errorf("%s: got %v", method, out)
+		}
+
+		if method == MethodGet {
+			if out["contentType"] != "" {
+				t.Errorf("%s: got %v", method, out)
+			}
+		} else if (out["a"] != 1.0) ||
+			(out["contentType"] != "application/json") {
+			t.Errorf("%s: got %v", method, out)
+		}
+	}
+
+	// Caller's headers are kept and the Request is unchanged
+	r = NewRequest(MethodPatch, "http://example.com/echo")
+	r.Headers.Set("Accept", "application/vnd.test+json")
+	r.Headers.Set("Content-Type", "application/merge-patch+json")
+
+	if e = c.DoJSON(r, map[string]int{"a": 2}, &out); e != nil {
+		t.Fatal(e)
+	}
+
+	if (out["accept"] != "application/vnd.test+json") ||
+		(out["contentType"] != "application/merge-patch+json") {
+		t.Errorf("got %v", out)
+	}
+
+	if r.Body != nil {
+		t.Error("caller's Request was modified")
+	}
+
+	// Empty body leaves out unchanged
+	out = map[string]any{"kept": true}
+
+	if e = c.GetJSON("http://example.com/empty", &out); e != nil {
+		t.Fatal(e)
+	} else if out["kept"] != true {
+		t.Errorf("got %v", out)
+	}
+
+	// No out
+	if e = c.GetJSON("http://example.com/echo", nil); e != nil {
+		t.Fatal(e)
+	}
+
+	if e = c.GetJSON("http://example.com/invalid", &out); e == nil {
+		t.Error("expected error for invalid JSON")
+	}
+
+	if e = c.PostJSON("http://example.com/echo", func() {}, nil); e == nil {
+		t.Error("expected error for unmarshalable input")
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	var body struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	var c *Client = NewClient(newJSONFake())
+	var e error
+	var se *StatusError
+
+	e = c.GetJSON("http://example.com/error", nil)
+	if !goerrors.As(e, &se) {
+		t.Fatalf("got %v, expected StatusError", e)
+	}
+
+	if e.Error() != "unexpected status 400 Bad Request" {
+		t.Errorf("got %q", e.Error())
+	}
+
+	if (se.StatusCode != http.StatusBadRequest) ||
+		(se.Header.Get("X-Test") != "a") {
+		t.Errorf("got %d, %v", se.StatusCode, se.Header)
+	}
+
+	if v, ok := se.JSON.(map[string]any); !ok || (v["message"] != "bad") {
+		t.Errorf("got JSON %v", se.JSON)
+	}
+
+	if e = se.Decode(&body); e != nil {
+		t.Fatal(e)
+	} else if (body.Code != 42) || (body.Message != "bad") {
+		t.Errorf("got %+v", body)
+	}
+
+	// Non-JSON bodies are kept raw, up to MaxErrorBodySize
+	e = c.GetJSON("http://example.com/html", nil)
+	if !goerrors.As(e, &se) {
+		t.Fatalf("got %v, expected StatusError", e)
+	}
+
+	if (se.JSON != nil) || !strings.HasPrefix(string(se.Body), "<html>") {
+		t.Errorf("got %v, %q", se.JSON, se.Body)
+	}
+
+	if e = se.Decode(&body); e == nil {
+		t.Error("expected error decoding HTML")
+	}
+
+	// Wrapped StatusErrors are still found
+	e = errors.Newf("request failed: %w", &StatusError{Status: "418"})
+	if !goerrors.As(e, &se) || (se.Status != "418") {
+		t.Errorf("got %v", e)
+	}
+}
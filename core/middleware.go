@@ -0,0 +1,87 @@
+package core
+
+import (
+	"context"
+
+	"github.com/mjwhitta/win/errors"
+)
+
+// Middleware wraps the send path of a Client to add cross-cutting
+// behavior (e.g. auth headers, signing, logging, metrics, or mocks).
+// It is given the next RoundTripFunc in the chain and returns a new
+// one. A Middleware can modify the Request before calling next (use
+// Request.Clone to avoid modifying the caller's Request), inspect or
+// replace the Response after, or short-circuit by returning w/o
+// calling next at all.
+//
+//	func auth(next core.RoundTripFunc) core.RoundTripFunc {
+//		return func(r *core.Request) (*core.Response, error) {
+//			r = r.Clone(r.Context())
+//			r.Headers.Set("Authorization", "Bearer "+token)
+//			return next(r)
+//		}
+//	}
+type Middleware func(next RoundTripFunc) RoundTripFunc
+
+// RoundTripFunc sends a single Request and returns its Response.
+type RoundTripFunc func(r *Request) (*Response, error)
+
+// wroteRequestKey is the context key for the function that is called
+// w/ each Request as it is given to the Backend.
+type wroteRequestKey struct{}
+
+// withWroteRequest will return a copy of ctx in which f is called w/
+// the Request as it is given to the Backend, after any inner
+// Middleware modified it. Any function already set is called first.
+func withWroteRequest(
+	ctx context.Context,
+	f func(r *Request),
+) context.Context {
+	var prev func(r *Request)
+
+	if prev, _ = ctx.Value(wroteRequestKey{}).(func(r *Request)); prev != nil {
+		return context.WithValue(
+			ctx,
+			wroteRequestKey{},
+			func(r *Request) {
+				prev(r)
+				f(r)
+			},
+		)
+	}
+
+	return context.WithValue(ctx, wroteRequestKey{}, f)
+}
+
+// roundTrip will return the Client's Middleware chain wrapped around
+// its Backend. The first Middleware is the outermost. The Backend is
+// still given the Client, so options like Timeout and
+// TLSClientConfig keep applying under the chain. Responses are
+// decompressed before any Middleware sees them.
+func (c *Client) roundTrip() RoundTripFunc {
+	var next RoundTripFunc = func(r *Request) (*Response, error) {
+		var wrote func(r *Request)
+
+		if c.Backend == nil {
+			return nil, errors.New("no backend configured")
+		}
+
+		// Let recorders see the Request as it is sent
+		wrote, _ = r.Context().Value(wroteRequestKey{}).(func(r *Request))
+		if wrote != nil {
+			wrote(r)
+		}
+
+		return c.Backend.Send(c, r)
+	}
+
+	if !c.DisableCompression {
+		next = decompress(next)
+	}
+
+	for i := len(c.Middleware) - 1; i >= 0; i-- {
+		next = c.Middleware[i](next)
+	}
+
+	return next
+}
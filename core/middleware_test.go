@@ -0,0 +1,158 @@
+package core
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// traceMiddleware will return a Middleware that records when the
+// Request enters and the Response leaves it.
+func traceMiddleware(name string, trace *[]string) Middleware {
+	return func(next RoundTripFunc) RoundTripFunc {
+		return func(r *Request) (*Response, error) {
+			var e error
+			var res *Response
+
+			*trace = append(*trace, name+">")
+			res, e = next(r)
+			*trace = append(*trace, "<"+name)
+
+			return res, e
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var b Backend
+	var c *Client
+	var e error
+	var out []byte
+	var req *Request
+	var res *Response
+	var srv *httptest.Server
+	var trace []string
+
+	srv = httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/redirect" {
+					http.Redirect(w, r, "/", http.StatusFound)
+					return
+				}
+
+				w.Write([]byte(r.Header.Get("Authorization")))
+			},
+		),
+	)
+	defer srv.Close()
+
+	if b, e = NewStd("test", ""); e != nil {
+		t.Fatal(e)
+	}
+
+	c = NewClient(b)
+	c.Middleware = []Middleware{
+		traceMiddleware("a", &trace),
+		traceMiddleware("b", &trace),
+		func(next RoundTripFunc) RoundTripFunc {
+			return func(r *Request) (*Response, error) {
+				r = r.Clone(r.Context())
+				r.Headers.Set("Authorization", "token")
+				return next(r)
+			}
+		},
+	}
+
+	req = NewRequest(MethodGet, srv.URL+"/redirect")
+
+	if res, e = c.Do(req); e != nil {
+		t.Fatal(e)
+	}
+	defer res.Body.Close()
+
+	// First is outermost, once per redirect hop
+	if v := strings.Join(trace, " "); v != "a> b> <b <a a> b> <b <a" {
+		t.Errorf("got order %q", v)
+	}
+
+	if out, _ = io.ReadAll(res.Body); string(out) != "token" {
+		t.Errorf("got %q, expected %q", out, "token")
+	}
+
+	// Caller's Request is unchanged
+	if v := req.Headers.Get("Authorization"); v != "" {
+		t.Errorf("got Authorization %q, expected none", v)
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	var b *closeBackend = &closeBackend{}
+	var c *Client = NewClient(b)
+	var e error
+	var out []byte
+	var res *Response
+
+	c.Middleware = []Middleware{
+		func(next RoundTripFunc) RoundTripFunc {
+			return func(r *Request) (*Response, error) {
+				return &Response{
+					Body:       io.NopCloser(strings.NewReader("mock")),
+					Header:     Header{},
+					StatusCode: http.StatusOK,
+				}, nil
+			}
+		},
+	}
+
+	// The Backend would fail
+	if res, e = c.Get("http://example.com"); e != nil {
+		t.Fatal(e)
+	}
+	defer res.Body.Close()
+
+	if out, _ = io.ReadAll(res.Body); string(out) != "mock" {
+		t.Errorf("got %q, expected %q", out, "mock")
+	}
+
+	// A nil Response w/o an error is an error
+	c.Middleware = []Middleware{
+		func(next RoundTripFunc) RoundTripFunc {
+			return func(r *Request) (*Response, error) {
+				return nil, nil
+			}
+		},
+	}
+
+	if _, e = c.Get("http://example.com"); e == nil {
+		t.Error("expected error for nil Response")
+	}
+}
+
+func TestRequestClone(t *testing.T) {
+	var cancel context.CancelFunc
+	var ctx context.Context
+	var r *Request = NewRequest(MethodGet, "http://example.com")
+	var r2 *Request
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	r.Headers.Add("X-Test", "a")
+	r.AddCookie(&Cookie{Name: "a", Value: "1"})
+
+	r2 = r.Clone(ctx)
+	r2.Headers.Add("X-Test", "b")
+	r2.AddCookie(&Cookie{Name: "b", Value: "2"})
+
+	if (len(r.Headers.Values("X-Test")) != 1) || (len(r.Cookies()) != 1) {
+		t.Error("Clone is not a deep copy")
+	}
+
+	if r2.Context() != ctx {
+		t.Error("context was not changed")
+	}
+}
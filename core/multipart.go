@@ -0,0 +1,207 @@
+package core
+
+import (
+	"io"
+	"mime/multipart"
+	"strings"
+)
+
+// Multipart is a builder for multipart/form-data request bodies. The
+// parts are streamed from their io.Readers when the Request is sent,
+// so files are never buffered in memory:
+//
+//	var f *os.File
+//	var m *core.Multipart = core.NewMultipart()
+//
+//	f, _ = os.Open("build.zip")
+//	defer f.Close()
+//
+//	m.AddField("version", "1.2.3")
+//	m.AddFile("artifact", "build.zip", "application/zip", f)
+//	res, e = c.PostMultipart(url, m)
+//
+// Readers are not closed by the Multipart. A Multipart can only be
+// sent again (e.g. on retry or 307 redirect) if every part can be
+// replayed, same as NewRequestWithBody.
+type Multipart struct {
+	boundary string
+	parts    []*multipartPart
+}
+
+type multipartPart struct {
+	body   io.Reader
+	header string
+	length int64
+	reset  func() (io.Reader, error)
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// NewMultipart will return a pointer to a new Multipart instance
+// with a random boundary.
+func NewMultipart() *Multipart {
+	return &Multipart{
+		boundary: multipart.NewWriter(io.Discard).Boundary(),
+	}
+}
+
+// NewMultipartRequest will return a pointer to a new Request instance
+// that streams the Multipart body, w/ the Content-Type header set.
+// The ContentLength is known if the length of every part is known
+// (see NewRequestWithBody), otherwise it is -1.
+func NewMultipartRequest(method, url string, m *Multipart) *Request {
+	var r *Request = NewRequestWithBody(method, url, nil)
+
+	r.Body, _ = m.reader(false)
+	r.ContentLength = m.length()
+	r.Headers.Set("Content-Type", m.ContentType())
+
+	if m.replayable() {
+		r.GetBody = func() (io.Reader, error) {
+			return m.reader(true)
+		}
+	}
+
+	return r
+}
+
+// AddField will add a form field.
+func (m *Multipart) AddField(name string, value string) {
+	var h Header = Header{}
+
+	h.Set(
+		"Content-Disposition",
+		`form-data; name="`+quoteEscaper.Replace(name)+`"`,
+	)
+
+	m.AddPart(h, strings.NewReader(value))
+}
+
+// AddFile will add a file, streamed from the provided io.Reader. If
+// contentType is empty, application/octet-stream is used.
+func (m *Multipart) AddFile(
+	field string,
+	filename string,
+	contentType string,
+	r io.Reader,
+) {
+	var h Header = Header{}
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	h.Set(
+		"Content-Disposition",
+		`form-data; name="`+quoteEscaper.Replace(field)+
+			`"; filename="`+quoteEscaper.Replace(filename)+`"`,
+	)
+	h.Set("Content-Type", contentType)
+
+	m.AddPart(h, r)
+}
+
+// AddPart will add a part w/ the provided headers (e.g.
+// Content-Disposition, Content-Type, Content-Transfer-Encoding),
+// streamed from the provided io.Reader.
+func (m *Multipart) AddPart(h Header, r io.Reader) {
+	var b strings.Builder
+	var p *multipartPart
+	var tmp *Request = NewRequestWithBody("", "", r)
+
+	// Same framing as mime/multipart
+	if len(m.parts) > 0 {
+		b.WriteString("\r\n")
+	}
+
+	b.WriteString("--" + m.boundary + "\r\n")
+
+	for _, k := range h.sortedKeys() {
+		for _, v := range h[k] {
+			b.WriteString(k + ": " + v + "\r\n")
+		}
+	}
+
+	b.WriteString("\r\n")
+
+	p = &multipartPart{
+		body:   tmp.Body,
+		header: b.String(),
+		length: tmp.ContentLength,
+		reset:  tmp.GetBody,
+	}
+
+	m.parts = append(m.parts, p)
+}
+
+// Boundary will return the boundary between parts.
+func (m *Multipart) Boundary() string {
+	return m.boundary
+}
+
+// ContentType will return the Content-Type header value, including
+// the boundary.
+func (m *Multipart) ContentType() string {
+	return "multipart/form-data; boundary=" + m.boundary
+}
+
+// footer will return the closing boundary.
+func (m *Multipart) footer() string {
+	if len(m.parts) == 0 {
+		return "--" + m.boundary + "--\r\n"
+	}
+
+	return "\r\n--" + m.boundary + "--\r\n"
+}
+
+// length will return the total length of the body, or -1 if unknown.
+func (m *Multipart) length() int64 {
+	var n int64 = int64(len(m.footer()))
+
+	for _, p := range m.parts {
+		if p.length < 0 {
+			return -1
+		}
+
+		n += int64(len(p.header)) + p.length
+	}
+
+	return n
+}
+
+// reader will return the body as a single io.Reader. If reset is
+// true, a new copy of each part is used.
+func (m *Multipart) reader(reset bool) (io.Reader, error) {
+	var body io.Reader
+	var e error
+	var readers []io.Reader
+
+	for _, p := range m.parts {
+		readers = append(readers, strings.NewReader(p.header))
+
+		if body = p.body; reset && (p.reset != nil) {
+			if body, e = p.reset(); e != nil {
+				return nil, e
+			}
+		}
+
+		if body != nil {
+			readers = append(readers, body)
+		}
+	}
+
+	readers = append(readers, strings.NewReader(m.footer()))
+
+	return io.MultiReader(readers...), nil
+}
+
+// replayable will return true if every part can be read again.
+func (m *Multipart) replayable() bool {
+	for _, p := range m.parts {
+		if (p.body != nil) && (p.reset == nil) {
+			return false
+		}
+	}
+
+	return true
+}
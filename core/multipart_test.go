@@ -0,0 +1,229 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newMultipartFake will return a Fake that echoes the parsed
+// multipart form as "length|field=value|file:name:type=content".
+func newMultipartFake() *Fake {
+	var f *Fake = NewFake()
+
+	f.HandleFunc(
+		"/redirect",
+		func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+		},
+	)
+	f.HandleFunc(
+		"/",
+		func(w http.ResponseWriter, r *http.Request) {
+			var b []byte
+			var mf multipart.File
+			var out []string
+
+			if e := r.ParseMultipartForm(1 << 20); e != nil {
+				http.Error(w, e.Error(), http.StatusBadRequest)
+				return
+			}
+
+			out = append(out, strconv.FormatInt(r.ContentLength, 10))
+
+			for k, vs := range r.MultipartForm.Value {
+				out = append(out, k+"="+strings.Join(vs, ","))
+			}
+
+			for k, fhs := range r.MultipartForm.File {
+				for _, fh := range fhs {
+					mf, _ = fh.Open()
+					b, _ = io.ReadAll(mf)
+					mf.Close()
+
+					out = append(
+						out,
+						"file:"+k+":"+fh.Filename+":"+
+							fh.Header.Get("Content-Type")+"="+string(b),
+					)
+				}
+			}
+
+			w.Write([]byte(strings.Join(out, "|")))
+		},
+	)
+
+	return f
+}
+
+func TestMultipart(t *testing.T) {
+	var c *Client = NewClient(newMultipartFake())
+	var e error
+	var m *Multipart
+	var out []byte
+	var r *Request
+	var res *Response
+
+	for _, test := range []struct {
+		expected string
+		file     io.Reader
+		url      string
+	}{
+		// Known length, replayed on redirect
+		{
+			"file:upload:a.txt:text/plain=data|" +
+				"name=value|quo\"te=x",
+			strings.NewReader("data"),
+			"http://example.com/redirect",
+		},
+		// Unknown length
+		{
+			"-1|file:upload:a.txt:text/plain=data|" +
+				"name=value|quo\"te=x",
+			io.MultiReader(strings.NewReader("data")),
+			"http://example.com/",
+		},
+	} {
+		m = NewMultipart()
+		m.AddField("name", "value")
+		m.AddField(`quo"te`, "x")
+		m.AddFile("upload", "a.txt", "text/plain", test.file)
+
+		r = NewMultipartRequest(MethodPost, test.url, m)
+
+		if v := r.Headers.Get("Content-Type"); v != m.ContentType() {
+			t.Errorf("got Content-Type %q", v)
+		}
+
+		if res, e = c.Do(r); e != nil {
+			t.Fatal(e)
+		}
+
+		out, _ = io.ReadAll(res.Body)
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			t.Fatalf("got status %d: %s", res.StatusCode, out)
+		}
+
+		// Known lengths are checked against what was sent
+		if r.ContentLength >= 0 {
+			test.expected = strconv.FormatInt(r.ContentLength, 10) +
+				"|" + test.expected
+		}
+
+		if v := sortedParts(string(out)); v != test.expected {
+			t.Errorf("got %q, expected %q", v, test.expected)
+		}
+	}
+}
+
+func TestMultipartLength(t *testing.T) {
+	var b []byte
+	var body io.Reader
+	var e error
+	var m *Multipart = NewMultipart()
+	var r *Request
+
+	// Empty
+	b, _ = io.ReadAll(NewMultipartRequest(MethodPost, "", m).Body)
+	if string(b) != "--"+m.Boundary()+"--\r\n" {
+		t.Errorf("got %q", b)
+	}
+
+	m.AddField("a", "1")
+	m.AddFile("f", "f.bin", "", bytes.NewReader([]byte{0, 1, 2}))
+	m.AddPart(Header{}, nil)
+
+	r = NewMultipartRequest(MethodPost, "", m)
+
+	if b, e = io.ReadAll(r.Body); e != nil {
+		t.Fatal(e)
+	}
+
+	if int64(len(b)) != r.ContentLength {
+		t.Errorf("got %d bytes, expected %d", len(b), r.ContentLength)
+	}
+
+	if !strings.Contains(string(b), "application/octet-stream") {
+		t.Error("expected default file Content-Type")
+	}
+
+	// Replay produces the same body
+	if r.GetBody == nil {
+		t.Fatal("expected GetBody")
+	}
+
+	for i := 0; i < 2; i++ {
+		if body, e = r.GetBody(); e != nil {
+			t.Fatal(e)
+		}
+
+		if b2, _ := io.ReadAll(body); !bytes.Equal(b, b2) {
+			t.Errorf("replay %d differs", i)
+		}
+	}
+
+	// Parts that can't be replayed
+	m.AddFile("g", "g.bin", "", io.MultiReader(strings.NewReader("x")))
+
+	r = NewMultipartRequest(MethodPost, "", m)
+
+	if (r.ContentLength != -1) || (r.GetBody != nil) {
+		t.Errorf("got %d, expected -1 and no GetBody", r.ContentLength)
+	}
+}
+
+func TestPostForm(t *testing.T) {
+	var c *Client
+	var e error
+	var f *Fake = NewFake()
+	var out []byte
+	var res *Response
+
+	f.HandleFunc(
+		"/",
+		func(w http.ResponseWriter, r *http.Request) {
+			r.ParseForm()
+			w.Write(
+				[]byte(
+					r.Header.Get("Content-Type") + "|" +
+						r.PostForm.Get("a") + "|" + r.PostForm.Get("b"),
+				),
+			)
+		},
+	)
+
+	c = NewClient(f)
+
+	res, e = c.PostForm(
+		"http://example.com/",
+		url.Values{"a": {"1 2"}, "b": {"&="}},
+	)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	out, _ = io.ReadAll(res.Body)
+	res.Body.Close()
+
+	if string(out) != "application/x-www-form-urlencoded|1 2|&=" {
+		t.Errorf("got %q", out)
+	}
+}
+
+// sortedParts will sort the "|" separated parts after the first,
+// since map order is random.
+func sortedParts(s string) string {
+	var parts []string = strings.Split(s, "|")
+
+	sort.Strings(parts[1:])
+
+	return strings.Join(parts, "|")
+}
@@ -0,0 +1,260 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mjwhitta/win/errors"
+)
+
+// Connection pool defaults, same as net/http.DefaultTransport.
+const (
+	DefaultIdleConnTimeout time.Duration = 90 * time.Second
+	DefaultMaxIdleConns    int           = 100
+)
+
+// connPool caches WinHTTP/WinINet connection handles keyed by scheme,
+// host, and port so they can be reused across requests. Idle handles
+// past the idle timeout are closed whenever the pool is used.
+type connPool struct {
+	close  func(hndl uintptr) error
+	closed bool
+	hosts  map[string]*hostConns
+	idle   int
+	mutex  sync.Mutex
+}
+
+// hostConns tracks the connection handles for a single key.
+type hostConns struct {
+	active int
+	idle   []idleConn
+
+	// wait is closed (and replaced) whenever a handle is released,
+	// to wake any requests waiting on MaxConnsPerHost
+	wait chan struct{}
+}
+
+type idleConn struct {
+	hndl  uintptr
+	since time.Time
+}
+
+// poolLimits are the Client options that control a connPool.
+type poolLimits struct {
+	disabled    bool
+	idleTimeout time.Duration
+	maxIdle     int
+	maxPerHost  int
+}
+
+func newConnPool(close func(hndl uintptr) error) *connPool {
+	return &connPool{close: close, hosts: map[string]*hostConns{}}
+}
+
+func (p *connPool) closeAll(hndls []uintptr) {
+	for _, hndl := range hndls {
+		p.close(hndl)
+	}
+}
+
+// closeIdle will close every idle handle.
+func (p *connPool) closeIdle() {
+	var hndls []uintptr
+
+	p.mutex.Lock()
+
+	for key, h := range p.hosts {
+		for _, conn := range h.idle {
+			hndls = append(hndls, conn.hndl)
+		}
+
+		p.idle -= len(h.idle)
+		h.idle = nil
+
+		if h.active == 0 {
+			delete(p.hosts, key)
+		}
+	}
+
+	p.mutex.Unlock()
+
+	p.closeAll(hndls)
+}
+
+// get will return an idle handle for key, if available, or open a
+// new one. If MaxConnsPerHost is reached, it blocks until a handle is
+// released or the context is done.
+func (p *connPool) get(
+	ctx context.Context,
+	key string,
+	limits poolLimits,
+	open func() (uintptr, error),
+) (uintptr, error) {
+	var conn idleConn
+	var e error
+	var expired []uintptr
+	var h *hostConns
+	var hndl uintptr
+	var wait chan struct{}
+
+	for {
+		p.mutex.Lock()
+
+		expired = p.prune(limits.idleTimeout)
+		h = p.host(key)
+
+		if n := len(h.idle); n > 0 {
+			// Most recently used first
+			conn = h.idle[n-1]
+			h.idle = h.idle[:n-1]
+			h.active++
+			p.idle--
+			p.mutex.Unlock()
+
+			p.closeAll(expired)
+
+			return conn.hndl, nil
+		}
+
+		if (limits.maxPerHost <= 0) || (h.active < limits.maxPerHost) {
+			h.active++
+			p.mutex.Unlock()
+
+			p.closeAll(expired)
+
+			if hndl, e = open(); e != nil {
+				p.put(key, 0, false, limits)
+				return 0, e
+			}
+
+			return hndl, nil
+		}
+
+		wait = h.wait
+		p.mutex.Unlock()
+
+		p.closeAll(expired)
+
+		select {
+		case <-ctx.Done():
+			return 0, errors.Newf("request aborted: %w", ctx.Err())
+		case <-wait:
+		}
+	}
+}
+
+// host will return the hostConns for key. The mutex must be held.
+func (p *connPool) host(key string) *hostConns {
+	var h *hostConns
+	var ok bool
+
+	if h, ok = p.hosts[key]; !ok {
+		h = &hostConns{wait: make(chan struct{})}
+		p.hosts[key] = h
+	}
+
+	return h
+}
+
+// prune will remove idle handles past the idle timeout and return
+// them to be closed. The mutex must be held.
+func (p *connPool) prune(timeout time.Duration) []uintptr {
+	var expired []uintptr
+	var keep []idleConn
+	var now time.Time = time.Now()
+
+	for key, h := range p.hosts {
+		keep = h.idle[:0]
+
+		for _, conn := range h.idle {
+			if now.Sub(conn.since) >= timeout {
+				expired = append(expired, conn.hndl)
+				p.idle--
+			} else {
+				keep = append(keep, conn)
+			}
+		}
+
+		h.idle = keep
+
+		if (h.active == 0) && (len(h.idle) == 0) {
+			delete(p.hosts, key)
+		}
+	}
+
+	return expired
+}
+
+// put will return a handle to the pool once a request is done with
+// it. If reuse is false, the pool is full, or the pool has been shut
+// down, the handle is closed.
+// A handle of 0 only frees the slot (e.g. when opening failed).
+func (p *connPool) put(
+	key string,
+	hndl uintptr,
+	reuse bool,
+	limits poolLimits,
+) {
+	var h *hostConns
+
+	p.mutex.Lock()
+
+	h = p.host(key)
+	h.active--
+
+	// Wake any waiting requests
+	close(h.wait)
+	h.wait = make(chan struct{})
+
+	// Handles in use when the pool was shut down are closed on return
+	if reuse && (hndl != 0) && !limits.disabled && !p.closed {
+		if p.idle < limits.maxIdle {
+			h.idle = append(h.idle, idleConn{hndl: hndl, since: time.Now()})
+			p.idle++
+			hndl = 0
+		}
+	}
+
+	if (h.active == 0) && (len(h.idle) == 0) {
+		delete(p.hosts, key)
+	}
+
+	p.mutex.Unlock()
+
+	if hndl != 0 {
+		p.close(hndl)
+	}
+}
+
+// shutdown will close every idle handle and stop keeping handles
+// that are returned afterwards, so that handles in use when the
+// backend is closed are not leaked.
+func (p *connPool) shutdown() {
+	p.mutex.Lock()
+	p.closed = true
+	p.mutex.Unlock()
+
+	p.closeIdle()
+}
+
+// poolLimits will return the connection pool options of the Client
+// with defaults applied.
+func (c *Client) poolLimits() poolLimits {
+	var limits poolLimits = poolLimits{
+		disabled:    c.DisableKeepAlives,
+		idleTimeout: c.IdleConnTimeout,
+		maxIdle:     c.MaxIdleConns,
+		maxPerHost:  c.MaxConnsPerHost,
+	}
+
+	if limits.idleTimeout <= 0 {
+		limits.idleTimeout = DefaultIdleConnTimeout
+	}
+
+	if limits.maxIdle == 0 {
+		limits.maxIdle = DefaultMaxIdleConns
+	}
+
+	return limits
+}
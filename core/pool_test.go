@@ -0,0 +1,141 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// testPool will return a connPool w/ fake handles that are counted in
+// liveHandles, same as the WinHTTP/WinINet backends.
+func testPool() (*connPool, func() (uintptr, error)) {
+	var next uintptr
+	var p *connPool = newConnPool(
+		func(hndl uintptr) error {
+			liveHandles.Add(-1)
+			return nil
+		},
+	)
+
+	return p, func() (uintptr, error) {
+		next++
+		liveHandles.Add(1)
+		return next, nil
+	}
+}
+
+func TestConnPoolContextCancel(t *testing.T) {
+	var before int64 = LiveHandles()
+	var cancel context.CancelFunc
+	var ctx context.Context
+	var e error
+	var hndl uintptr
+	var limits poolLimits = poolLimits{
+		idleTimeout: time.Minute,
+		maxIdle:     DefaultMaxIdleConns,
+		maxPerHost:  1,
+	}
+	var open func() (uintptr, error)
+	var p *connPool
+
+	p, open = testPool()
+
+	if hndl, e = p.get(context.Background(), "a", limits, open); e != nil {
+		t.Fatal(e)
+	}
+
+	// Blocked on MaxConnsPerHost until the context is done
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, e = p.get(ctx, "a", limits, open); e == nil {
+		t.Fatal("expected error")
+	}
+
+	p.put("a", hndl, true, limits)
+	p.shutdown()
+
+	if n := LiveHandles() - before; n != 0 {
+		t.Fatalf("got %d live handles, expected 0", n)
+	}
+}
+
+func TestConnPoolEarlyClose(t *testing.T) {
+	var before int64 = LiveHandles()
+	var e error
+	var hndl uintptr
+	var limits poolLimits = poolLimits{
+		idleTimeout: time.Minute,
+		maxIdle:     DefaultMaxIdleConns,
+	}
+	var open func() (uintptr, error)
+	var p *connPool
+
+	p, open = testPool()
+
+	// A body closed before EOF, or a failed request, is not reused
+	for i := 0; i < 3; i++ {
+		if hndl, e = p.get(context.Background(), "a", limits, open); e != nil {
+			t.Fatal(e)
+		}
+
+		p.put("a", hndl, false, limits)
+	}
+
+	if n := LiveHandles() - before; n != 0 {
+		t.Fatalf("got %d live handles, expected 0", n)
+	}
+
+	p.shutdown()
+
+	if n := LiveHandles() - before; n != 0 {
+		t.Fatalf("got %d live handles after shutdown, expected 0", n)
+	}
+}
+
+func TestConnPoolShutdown(t *testing.T) {
+	var before int64 = LiveHandles()
+	var busy uintptr
+	var e error
+	var hndl uintptr
+	var limits poolLimits = poolLimits{
+		idleTimeout: time.Minute,
+		maxIdle:     DefaultMaxIdleConns,
+	}
+	var open func() (uintptr, error)
+	var p *connPool
+
+	p, open = testPool()
+
+	if hndl, e = p.get(context.Background(), "a", limits, open); e != nil {
+		t.Fatal(e)
+	}
+
+	if busy, e = p.get(context.Background(), "a", limits, open); e != nil {
+		t.Fatal(e)
+	}
+
+	// One idle, one in flight
+	p.put("a", hndl, true, limits)
+
+	if n := LiveHandles() - before; n != 2 {
+		t.Fatalf("got %d live handles, expected 2", n)
+	}
+
+	p.shutdown()
+
+	if n := LiveHandles() - before; n != 1 {
+		t.Fatalf("got %d live handles after shutdown, expected 1", n)
+	}
+
+	// Returned after shutdown, so must not be kept
+	p.put("a", busy, true, limits)
+
+	if n := LiveHandles() - before; n != 0 {
+		t.Fatalf("got %d live handles after put, expected 0", n)
+	}
+
+	if len(p.hosts) != 0 {
+		t.Fatalf("got %d hosts after shutdown, expected 0", len(p.hosts))
+	}
+}
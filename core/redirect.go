@@ -0,0 +1,113 @@
+package core
+
+import (
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/mjwhitta/win/errors"
+)
+
+// DefaultMaxRedirects is the number of redirects followed when a
+// Client's MaxRedirects is 0, same as net/http.
+const DefaultMaxRedirects int = 10
+
+// Redirect describes a single hop of a redirect chain.
+type Redirect struct {
+	// Location is the resolved URL the server redirected to.
+	Location string
+
+	// StatusCode is the redirect status code (e.g. 302).
+	StatusCode int
+
+	// URL is the URL that was requested.
+	URL string
+}
+
+func isRedirect(code int) bool {
+	switch code {
+	case 301, 302, 303, 307, 308:
+		return true
+	}
+
+	return false
+}
+
+// redirectRequest will return the Request to send to follow the
+// redirect, or nil if it can't be followed.
+func redirectRequest(
+	r *Request,
+	res *Response,
+) (*Request, error) {
+	var body io.Reader
+	var e error
+	var loc string
+	var method string = r.Method
+	var next *Request
+	var prev *url.URL
+	var uri *url.URL
+
+	if loc = res.Header.Get("Location"); loc == "" {
+		return nil, nil
+	}
+
+	if prev, e = url.Parse(r.URL); e != nil {
+		return nil, errors.Newf("failed to parse url %s: %w", r.URL, e)
+	}
+
+	if uri, e = prev.Parse(loc); e != nil {
+		e = errors.Newf("failed to parse Location %s: %w", loc, e)
+		return nil, e
+	}
+
+	switch res.StatusCode {
+	case 301, 302, 303:
+		// Same as browsers and net/http, change to GET w/o a body
+		if (method != MethodGet) && (method != MethodHead) {
+			method = MethodGet
+		}
+	case 307, 308:
+		// Method and body must be preserved, so the body must be
+		// replayable
+		if r.Body != nil {
+			if r.GetBody == nil {
+				return nil, nil
+			}
+
+			if body, e = r.GetBody(); e != nil {
+				return nil, errors.Newf("failed to get body: %w", e)
+			}
+		}
+	}
+
+	next = NewRequestWithBody(method, uri.String(), nil)
+	next.ctx = r.ctx
+
+	if body != nil {
+		next.Body = body
+		next.ContentLength = r.ContentLength
+		next.GetBody = r.GetBody
+	}
+
+	next.Headers = r.Headers.Clone()
+	if next.Headers == nil {
+		next.Headers = Header{}
+	}
+
+	// Drop headers describing a dropped body
+	if body == nil {
+		next.Headers.Del("Content-Encoding")
+		next.Headers.Del("Content-Length")
+		next.Headers.Del("Content-Type")
+	}
+
+	// Don't leak credentials to a different host
+	if !strings.EqualFold(prev.Hostname(), uri.Hostname()) {
+		next.Headers.Del("Authorization")
+		next.Headers.Del("Cookie")
+	} else {
+		next.cookies = append(next.cookies, r.cookies...)
+	}
+
+	return next, nil
+}
@@ -0,0 +1,251 @@
+package core
+
+import (
+	"bytes"
+	goerrors "errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newRedirectServer will return a server that redirects /<code> to
+// /echo w/ the provided status code. The echo handler writes the
+// method, the credentials that were sent, and the body.
+func newRedirectServer(t *testing.T, other string) *httptest.Server {
+	return httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				var b []byte
+
+				switch r.URL.Path {
+				case "/301":
+					http.Redirect(w, r, "/302", http.StatusMovedPermanently)
+				case "/302":
+					http.Redirect(w, r, "/echo", http.StatusFound)
+				case "/303":
+					http.Redirect(w, r, "/echo", http.StatusSeeOther)
+				case "/307":
+					http.Redirect(w, r, "/echo", http.StatusTemporaryRedirect)
+				case "/308":
+					http.Redirect(w, r, "/echo", http.StatusPermanentRedirect)
+				case "/loop":
+					http.Redirect(w, r, "/loop", http.StatusFound)
+				case "/other":
+					http.Redirect(w, r, other+"/echo", http.StatusFound)
+				case "/echo":
+					b, _ = io.ReadAll(r.Body)
+
+					w.Write(
+						[]byte(
+							strings.Join(
+								[]string{
+									r.Method,
+									r.Header.Get("Authorization"),
+									r.Header.Get("Cookie"),
+									r.Header.Get("Content-Type"),
+									string(b),
+								},
+								"|",
+							),
+						),
+					)
+				default:
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+			},
+		),
+	)
+}
+
+func TestRedirect(t *testing.T) {
+	var b Backend
+	var bodies []*closeRecorder
+	var c *Client
+	var e error
+	var other *httptest.Server
+	var out []byte
+	var req *Request
+	var res *Response
+	var srv *httptest.Server
+
+	// Same server, but a different host
+	other = newRedirectServer(t, "")
+	defer other.Close()
+
+	srv = newRedirectServer(
+		t,
+		strings.Replace(other.URL, "127.0.0.1", "localhost", 1),
+	)
+	defer srv.Close()
+
+	if b, e = NewStd("test", ""); e != nil {
+		t.Fatal(e)
+	}
+
+	c = NewClient(b)
+
+	for _, test := range []struct {
+		expected string
+		path     string
+	}{
+		// Method and body are dropped
+		{"GET|secret|sid=abc||", "/301"},
+		{"GET|secret|sid=abc||", "/303"},
+
+		// Method and body are replayed
+		{"POST|secret|sid=abc|text/plain|body", "/307"},
+		{"POST|secret|sid=abc|text/plain|body", "/308"},
+
+		// Credentials are not sent to a different host
+		{"GET||||", "/other"},
+	} {
+		bodies = nil
+
+		req = NewRequest(MethodPost, srv.URL+test.path, []byte("body"))
+		req.Headers.Set("Authorization", "secret")
+		req.Headers.Set("Content-Type", "text/plain")
+		req.AddCookie(&Cookie{Name: "sid", Value: "abc"})
+
+		// Track the bodies of each hop
+		req.GetBody = func() (io.Reader, error) {
+			var body *closeRecorder = &closeRecorder{
+				Reader: strings.NewReader("body"),
+			}
+
+			bodies = append(bodies, body)
+			return body, nil
+		}
+
+		if res, e = c.Do(req); e != nil {
+			t.Fatalf("%s: %s", test.path, e)
+		}
+
+		out, _ = io.ReadAll(res.Body)
+		if string(out) != test.expected {
+			t.Errorf(
+				"%s: got %q, expected %q",
+				test.path,
+				out,
+				test.expected,
+			)
+		}
+
+		res.Body.Close()
+
+		for _, body := range bodies {
+			if !body.closed.Load() {
+				t.Errorf("%s: redirect body was not closed", test.path)
+			}
+		}
+	}
+
+	// History is recorded oldest first
+	if res, e = c.Get(srv.URL + "/301"); e != nil {
+		t.Fatal(e)
+	}
+	res.Body.Close()
+
+	if len(res.Redirects) != 2 {
+		t.Fatalf("got %d redirects, expected 2", len(res.Redirects))
+	}
+
+	for i, expected := range []Redirect{
+		{srv.URL + "/302", 301, srv.URL + "/301"},
+		{srv.URL + "/echo", 302, srv.URL + "/302"},
+	} {
+		if res.Redirects[i] != expected {
+			t.Errorf("got %+v, expected %+v", res.Redirects[i], expected)
+		}
+	}
+
+	// Unreplayable bodies stop at the 307
+	req = NewRequestWithBody(
+		MethodPost,
+		srv.URL+"/307",
+		io.MultiReader(bytes.NewReader([]byte("body"))),
+	)
+
+	if res, e = c.Do(req); e != nil {
+		t.Fatal(e)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusTemporaryRedirect {
+		t.Errorf("got status %d, expected 307", res.StatusCode)
+	}
+}
+
+func TestRedirectPolicy(t *testing.T) {
+	var b Backend
+	var c *Client
+	var done error = goerrors.New("done")
+	var e error
+	var hops int
+	var res *Response
+	var srv *httptest.Server = newRedirectServer(t, "")
+
+	defer srv.Close()
+
+	if b, e = NewStd("test", ""); e != nil {
+		t.Fatal(e)
+	}
+
+	c = NewClient(b)
+
+	// DefaultMaxRedirects
+	if _, e = c.Get(srv.URL + "/loop"); e == nil {
+		t.Error("expected error after too many redirects")
+	}
+
+	c.MaxRedirects = 1
+
+	if _, e = c.Get(srv.URL + "/301"); e == nil {
+		t.Error("expected error after 1 redirect")
+	}
+
+	// CheckRedirect owns the policy
+	c.MaxRedirects = 0
+	c.CheckRedirect = func(req *Request, via []*Request) error {
+		if hops = len(via); hops > 2*DefaultMaxRedirects {
+			return done
+		}
+
+		return nil
+	}
+
+	if _, e = c.Get(srv.URL + "/loop"); !goerrors.Is(e, done) {
+		t.Errorf("got %v, expected hook error", e)
+	} else if hops <= DefaultMaxRedirects {
+		t.Errorf("stopped after %d redirects", hops)
+	}
+
+	c.CheckRedirect = func(req *Request, via []*Request) error {
+		return ErrUseLastResponse
+	}
+
+	if res, e = c.Get(srv.URL + "/302"); e != nil {
+		t.Fatal(e)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusFound {
+		t.Errorf("got status %d, expected 302", res.StatusCode)
+	}
+
+	// DisableRedirects
+	c.CheckRedirect = nil
+	c.DisableRedirects = true
+
+	if res, e = c.Get(srv.URL + "/303"); e != nil {
+		t.Fatal(e)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusSeeOther {
+		t.Errorf("got status %d, expected 303", res.StatusCode)
+	} else if len(res.Redirects) != 0 {
+		t.Errorf("got %d redirects, expected 0", len(res.Redirects))
+	}
+}
@@ -0,0 +1,214 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"strings"
+)
+
+// Request is a struct containing common HTTP request data.
+type Request struct {
+	// Body is streamed to the server when the Request is sent. A nil
+	// Body means the Request has no body. If Body is also an
+	// io.Closer, it is closed once sent.
+	Body io.Reader
+
+	// ContentLength is the length of Body, if known. A value of -1
+	// (or 0 with a non-nil Body) means the length is unknown and the
+	// Body will be sent with chunked Transfer-Encoding.
+	ContentLength int64
+
+	cookies []*Cookie
+	ctx     context.Context
+
+	// GetBody is an optional function that returns a new copy of
+	// Body. It is used when a Request must be sent again (e.g. a 307
+	// or 308 redirect). It is set automatically by NewRequest and
+	// NewRequestWithBody when possible.
+	GetBody func() (io.Reader, error)
+
+	Headers Header
+	Method  string
+	URL     string
+}
+
+// NewRequest will return a pointer to a new Request instance.
+func NewRequest(method, url string, body ...[]byte) *Request {
+	var r *Request = NewRequestWithBody(method, url, nil)
+
+	if (len(body) > 0) && (len(body[0]) > 0) {
+		r.Body = bytes.NewReader(body[0])
+		r.ContentLength = int64(len(body[0]))
+		r.GetBody = func() (io.Reader, error) {
+			return bytes.NewReader(body[0]), nil
+		}
+	}
+
+	return r
+}
+
+// NewRequestWithContext will return a pointer to a new Request
+// instance that is aborted once the provided context is done.
+func NewRequestWithContext(
+	ctx context.Context,
+	method string,
+	url string,
+	body ...[]byte,
+) *Request {
+	var r *Request = NewRequest(method, url, body...)
+
+	r.ctx = ctx
+	return r
+}
+
+// NewRequestWithBody will return a pointer to a new Request instance
+// that streams the provided io.Reader. The ContentLength is
+// determined automatically for common types (bytes.Buffer,
+// bytes.Reader, strings.Reader, os.File). Otherwise it is -1 and can
+// be set manually, if known.
+func NewRequestWithBody(method, url string, body io.Reader) *Request {
+	var r *Request = &Request{
+		Body:    body,
+		Headers: Header{},
+		Method:  method,
+		URL:     url,
+	}
+
+	switch b := body.(type) {
+	case nil:
+	case *bytes.Buffer:
+		buf := b.Bytes()
+
+		r.ContentLength = int64(len(buf))
+		r.GetBody = func() (io.Reader, error) {
+			return bytes.NewReader(buf), nil
+		}
+	case *bytes.Reader:
+		snapshot := *b
+
+		r.ContentLength = int64(b.Len())
+		r.GetBody = func() (io.Reader, error) {
+			var tmp bytes.Reader = snapshot
+			return &tmp, nil
+		}
+	case *strings.Reader:
+		snapshot := *b
+
+		r.ContentLength = int64(b.Len())
+		r.GetBody = func() (io.Reader, error) {
+			var tmp strings.Reader = snapshot
+			return &tmp, nil
+		}
+	case interface{ Len() int }:
+		r.ContentLength = int64(b.Len())
+	case *os.File:
+		r.ContentLength = -1
+
+		if fi, e := b.Stat(); (e == nil) && fi.Mode().IsRegular() {
+			if off, e := b.Seek(0, io.SeekCurrent); e == nil {
+				r.ContentLength = fi.Size() - off
+			}
+		}
+	default:
+		r.ContentLength = -1
+	}
+
+	if (r.Body != nil) && (r.ContentLength == 0) {
+		r.Body = nil
+		r.GetBody = nil
+	}
+
+	return r
+}
+
+// AddCookie will add a Cookie to the Request.
+func (r *Request) AddCookie(cookie *Cookie) {
+	for i, c := range r.cookies {
+		if cookie.Name == c.Name {
+			r.cookies = append(r.cookies[:i], r.cookies[i+1:]...)
+			break
+		}
+	}
+
+	r.cookies = append(r.cookies, cookie)
+}
+
+// Clone will return a deep copy of the Request with its context
+// changed to ctx. The Body is shared, same as net/http, so only one
+// of the Requests should be sent, unless GetBody is set.
+func (r *Request) Clone(ctx context.Context) *Request {
+	var r2 *Request = r.WithContext(ctx)
+
+	r2.cookies = append([]*Cookie(nil), r.cookies...)
+	r2.Headers = r.Headers.Clone()
+
+	if r2.Headers == nil {
+		r2.Headers = Header{}
+	}
+
+	return r2
+}
+
+func (r *Request) closeBody() {
+	if c, ok := r.Body.(io.Closer); ok {
+		c.Close()
+	}
+}
+
+// Context will return the Request's context. To change it, use
+// WithContext.
+func (r *Request) Context() context.Context {
+	if r.ctx != nil {
+		return r.ctx
+	}
+
+	return context.Background()
+}
+
+// Cookie will return the named Cookie provided in the Request or
+// ErrNoCookie, if not found.
+func (r *Request) Cookie(name string) (*Cookie, error) {
+	for _, c := range r.cookies {
+		if name == c.Name {
+			return c, nil
+		}
+	}
+
+	return nil, ErrNoCookie
+}
+
+// Cookies will return the HTTP cookies provided in the Request.
+func (r *Request) Cookies() []*Cookie {
+	return r.cookies
+}
+
+// outgoingLength will return the number of bytes that will be sent,
+// or -1 if unknown.
+func (r *Request) outgoingLength() int64 {
+	if r.Body == nil {
+		return 0
+	}
+
+	if r.ContentLength > 0 {
+		return r.ContentLength
+	}
+
+	return -1
+}
+
+// WithContext will return a shallow copy of the Request with its
+// context changed to ctx.
+func (r *Request) WithContext(ctx context.Context) *Request {
+	var r2 *Request = new(Request)
+
+	if ctx == nil {
+		panic("nil context")
+	}
+
+	*r2 = *r
+	r2.ctx = ctx
+
+	return r2
+}
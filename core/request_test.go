@@ -0,0 +1,214 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// closeRecorder records whether the Request body was closed.
+type closeRecorder struct {
+	io.Reader
+	closed atomic.Bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed.Store(true)
+	return nil
+}
+
+func TestNewRequestWithBody(t *testing.T) {
+	var e error
+	var f *os.File
+	var path string = filepath.Join(t.TempDir(), "body")
+	var r *Request
+
+	if e = os.WriteFile(path, []byte("0123456789"), 0o600); e != nil {
+		t.Fatal(e)
+	}
+
+	if f, e = os.Open(path); e != nil {
+		t.Fatal(e)
+	}
+	defer f.Close()
+
+	f.Seek(4, io.SeekStart)
+
+	for _, test := range []struct {
+		body   io.Reader
+		length int64
+		name   string
+	}{
+		{nil, 0, "nil"},
+		{bytes.NewReader([]byte("abc")), 3, "bytes.Reader"},
+		{bytes.NewBufferString("abcd"), 4, "bytes.Buffer"},
+		{strings.NewReader("abcde"), 5, "strings.Reader"},
+		{f, 6, "os.File"},
+		{io.MultiReader(strings.NewReader("a")), -1, "unknown"},
+	} {
+		r = NewRequestWithBody(MethodPost, "http://example.com", test.body)
+
+		if r.ContentLength != test.length {
+			t.Errorf(
+				"%s: got length %d, expected %d",
+				test.name,
+				r.ContentLength,
+				test.length,
+			)
+		}
+	}
+
+	// Empty bodies are dropped
+	r = NewRequestWithBody(MethodPost, "http://example.com", &bytes.Buffer{})
+	if r.Body != nil {
+		t.Error("expected nil Body for empty buffer")
+	}
+
+	if r = NewRequest(MethodPost, "http://example.com", nil); r.Body != nil {
+		t.Error("expected nil Body for nil []byte")
+	}
+}
+
+// TestStdStreaming checks that bodies are streamed, w/ chunked
+// Transfer-Encoding when the length is unknown.
+func TestStdStreaming(t *testing.T) {
+	var b Backend
+	var body *closeRecorder
+	var c *Client
+	var e error
+	var out []byte
+	var pr *io.PipeReader
+	var pw *io.PipeWriter
+	var read chan struct{} = make(chan struct{})
+	var req *Request
+	var res *Response
+	var srv *httptest.Server
+
+	srv = httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				var buf []byte = make([]byte, 5)
+				var e error
+				var rest []byte
+
+				// The first part arrives before the rest is written
+				if _, e = io.ReadFull(r.Body, buf); e != nil {
+					t.Error(e)
+				}
+
+				if r.URL.Path == "/stream" {
+					close(read)
+				}
+
+				rest, _ = io.ReadAll(r.Body)
+
+				w.Write(
+					[]byte(
+						strings.Join(r.TransferEncoding, ",") + " " +
+							string(buf) + string(rest),
+					),
+				)
+			},
+		),
+	)
+	defer srv.Close()
+
+	if b, e = NewStd("test", ""); e != nil {
+		t.Fatal(e)
+	}
+
+	c = NewClient(b)
+
+	pr, pw = io.Pipe()
+
+	go func() {
+		pw.Write([]byte("first"))
+
+		select {
+		case <-read:
+		case <-time.After(5 * time.Second):
+			t.Error("body was not streamed")
+		}
+
+		pw.Write([]byte(" second"))
+		pw.Close()
+	}()
+
+	body = &closeRecorder{Reader: pr}
+	req = NewRequestWithBody(MethodPost, srv.URL+"/stream", body)
+
+	if res, e = c.Do(req); e != nil {
+		t.Fatal(e)
+	}
+
+	if out, _ = io.ReadAll(res.Body); string(out) != "chunked first second" {
+		t.Errorf("got %q, expected chunked body", out)
+	}
+
+	if !body.closed.Load() {
+		t.Error("Body was not closed")
+	}
+
+	// Known length is sent w/ Content-Length
+	req = NewRequestWithBody(
+		MethodPut,
+		srv.URL,
+		strings.NewReader("known length"),
+	)
+
+	if res, e = c.Do(req); e != nil {
+		t.Fatal(e)
+	}
+
+	if out, _ = io.ReadAll(res.Body); string(out) != " known length" {
+		t.Errorf("got %q, expected identity body", out)
+	}
+}
+
+// TestWriteBody checks the chunked framing used by the Windows
+// backends, which have to write the body themselves.
+func TestWriteBody(t *testing.T) {
+	var b []byte
+	var buf bytes.Buffer
+	var e error
+	var r *Request
+
+	r = NewRequestWithBody(
+		MethodPost,
+		"http://example.com",
+		io.MultiReader(strings.NewReader("abc"), strings.NewReader("de")),
+	)
+
+	if e = writeBody(&buf, r); e != nil {
+		t.Fatal(e)
+	}
+
+	if !strings.HasSuffix(buf.String(), "0\r\n\r\n") {
+		t.Errorf("missing final chunk: %q", buf.String())
+	}
+
+	b, e = io.ReadAll(httputil.NewChunkedReader(&buf))
+	if e != nil {
+		t.Fatal(e)
+	} else if string(b) != "abcde" {
+		t.Errorf("got %q, expected %q", b, "abcde")
+	}
+
+	// Known length is written as is
+	buf.Reset()
+	r = NewRequest(MethodPost, "http://example.com", []byte("abc"))
+
+	if e = writeBody(&buf, r); e != nil {
+		t.Fatal(e)
+	} else if buf.String() != "abc" {
+		t.Errorf("got %q, expected %q", buf.String(), "abc")
+	}
+}
@@ -0,0 +1,63 @@
+package core
+
+import "io"
+
+// Response is a struct containing common HTTP response data.
+type Response struct {
+	// Body is read lazily from the server. The caller must close it
+	// to release the underlying connection.
+	Body io.ReadCloser
+
+	cookies []*Cookie
+
+	// ContentLength is the value of the Content-Length header, or -1
+	// if unknown.
+	ContentLength int64
+
+	Header     Header
+	Proto      string
+	ProtoMajor int
+	ProtoMinor int
+
+	// Redirects is the chain of redirects that was followed to get
+	// this Response, oldest first.
+	Redirects []Redirect
+
+	Status     string
+	StatusCode int
+
+	// Uncompressed reports whether the Response was sent compressed
+	// and was decoded by the Client. The Content-Encoding and
+	// Content-Length headers are removed and ContentLength is -1,
+	// same as net/http.
+	Uncompressed bool
+}
+
+// AddCookie will add a Cookie to the Response.
+func (r *Response) AddCookie(cookie *Cookie) {
+	for i, c := range r.cookies {
+		if cookie.Name == c.Name {
+			r.cookies = append(r.cookies[:i], r.cookies[i+1:]...)
+			break
+		}
+	}
+
+	r.cookies = append(r.cookies, cookie)
+}
+
+// Cookie will return the named Cookie provided in the Response or
+// ErrNoCookie, if not found.
+func (r *Response) Cookie(name string) (*Cookie, error) {
+	for _, c := range r.cookies {
+		if name == c.Name {
+			return c, nil
+		}
+	}
+
+	return nil, ErrNoCookie
+}
+
+// Cookies will return the HTTP cookies provided in the Response.
+func (r *Response) Cookies() []*Cookie {
+	return r.cookies
+}
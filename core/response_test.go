@@ -0,0 +1,89 @@
+package core
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestContentLength(t *testing.T) {
+	for _, test := range []struct {
+		hdrs   Header
+		length int64
+	}{
+		{nil, -1},
+		{Header{"Content-Length": {"42"}}, 42},
+		{Header{"Content-Length": {" 7 "}}, 7},
+		{Header{"Content-Length": {"-1"}}, -1},
+		{Header{"Content-Length": {"x"}}, -1},
+		{Header{"Content-Length": {}}, -1},
+	} {
+		if n := contentLength(test.hdrs); n != test.length {
+			t.Errorf("%v: got %d, expected %d", test.hdrs, n, test.length)
+		}
+	}
+}
+
+// TestLazyBody checks that Do returns once the headers arrive and
+// that the body is read as the caller reads it.
+func TestLazyBody(t *testing.T) {
+	var b Backend
+	var buf []byte = make([]byte, 5)
+	var c *Client
+	var done chan struct{} = make(chan struct{})
+	var e error
+	var res *Response
+	var srv *httptest.Server
+
+	srv = httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("first"))
+				w.(http.Flusher).Flush()
+
+				// The rest is only sent once the client has read
+				// the first part
+				select {
+				case <-done:
+				case <-time.After(5 * time.Second):
+					t.Error("body was not read lazily")
+				}
+
+				w.Write([]byte("second"))
+			},
+		),
+	)
+	defer srv.Close()
+
+	if b, e = NewStd("test", ""); e != nil {
+		t.Fatal(e)
+	}
+
+	c = NewClient(b)
+
+	if res, e = c.Get(srv.URL); e != nil {
+		t.Fatal(e)
+	}
+	defer res.Body.Close()
+
+	// No Content-Length b/c of the flush
+	if res.ContentLength != -1 {
+		t.Errorf("got length %d, expected -1", res.ContentLength)
+	}
+
+	if _, e = io.ReadFull(res.Body, buf); e != nil {
+		t.Fatal(e)
+	} else if string(buf) != "first" {
+		t.Errorf("got %q, expected %q", buf, "first")
+	}
+
+	close(done)
+
+	if buf, e = io.ReadAll(res.Body); e != nil {
+		t.Fatal(e)
+	} else if string(buf) != "second" {
+		t.Errorf("got %q, expected %q", buf, "second")
+	}
+}
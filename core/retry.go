@@ -0,0 +1,325 @@
+package core
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	goerrors "errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mjwhitta/win/errors"
+)
+
+// RetryPolicy describes when and how a Client retries a failed
+// request. Only idempotent requests are retried by default. Each
+// redirect hop is retried separately.
+type RetryPolicy struct {
+	// MaxBackoff is the maximum delay between attempts, including
+	// any Retry-After delay. If 0, DefaultMaxBackoff is used.
+	MaxBackoff time.Duration
+
+	// MaxRetries is the maximum number of retries after the first
+	// attempt. If 0, DefaultMaxRetries is used.
+	MaxRetries int
+
+	// MinBackoff is the delay before the first retry, which doubles
+	// for each retry after. A random jitter of up to the full delay
+	// is applied. If 0, DefaultMinBackoff is used.
+	MinBackoff time.Duration
+
+	// RetryNonIdempotent allows methods like POST and PATCH to be
+	// retried. By default, they are only retried if the Request has
+	// an Idempotency-Key header, same as net/http.
+	RetryNonIdempotent bool
+
+	// ShouldRetry, if not nil, replaces the default classification
+	// of failures. It is given the Response or error of the latest
+	// attempt and returns whether to retry. The idempotency check and
+	// MaxRetries still apply.
+	ShouldRetry func(r *Request, res *Response, e error) bool
+
+	// StatusCodes are the response status codes that are retried. If
+	// nil, DefaultRetryStatusCodes is used.
+	StatusCodes []int
+}
+
+// Retry defaults.
+const (
+	DefaultMaxBackoff time.Duration = 30 * time.Second
+	DefaultMaxRetries int           = 3
+	DefaultMinBackoff time.Duration = 100 * time.Millisecond
+)
+
+// DefaultRetryStatusCodes are the response status codes that are
+// retried by default, as they are often temporary.
+var DefaultRetryStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// retryErrnos are the WinHTTP/WinINet error codes for connection
+// failures and timeouts. The libraries share these values, so each is
+// labeled w/ its WinHTTP name, then its WinINet name.
+var retryErrnos = map[syscall.Errno]bool{
+	// ERROR_WINHTTP_TIMEOUT, ERROR_INTERNET_TIMEOUT
+	12002: true,
+
+	// ERROR_WINHTTP_CANNOT_CONNECT, ERROR_INTERNET_CANNOT_CONNECT
+	12029: true,
+
+	// ERROR_WINHTTP_CONNECTION_ERROR, ERROR_INTERNET_CONNECTION_ABORTED
+	12030: true,
+
+	// Unused by WinHTTP, ERROR_INTERNET_CONNECTION_RESET
+	12031: true,
+
+	// ERROR_WINHTTP_INVALID_SERVER_RESPONSE,
+	// ERROR_HTTP_INVALID_SERVER_RESPONSE
+	12152: true,
+}
+
+// isIdempotent will return true if the Request can safely be sent
+// more than once.
+func isIdempotent(r *Request) bool {
+	switch r.Method {
+	case "", MethodDelete, MethodGet, MethodHead, MethodOptions:
+		return true
+	case MethodPut, MethodTrace:
+		return true
+	}
+
+	// Same as net/http
+	return (r.Headers.Get("Idempotency-Key") != "") ||
+		(r.Headers.Get("X-Idempotency-Key") != "")
+}
+
+// isRetryableError will return true for connection failures and
+// timeouts, which are often temporary.
+func isRetryableError(e error) bool {
+	var errno syscall.Errno
+	var netErr net.Error
+
+	switch {
+	case e == nil:
+		return false
+	case goerrors.Is(e, io.EOF), goerrors.Is(e, io.ErrUnexpectedEOF):
+		// Server closed the connection early
+		return true
+	case goerrors.Is(e, syscall.ECONNABORTED):
+		return true
+	case goerrors.Is(e, syscall.ECONNREFUSED):
+		return true
+	case goerrors.Is(e, syscall.ECONNRESET):
+		return true
+	case goerrors.Is(e, syscall.EPIPE):
+		return true
+	case goerrors.As(e, &netErr) && netErr.Timeout():
+		return true
+	case goerrors.As(e, &errno) && retryErrnos[errno]:
+		return true
+	}
+
+	return false
+}
+
+// jitter will return a random duration in [0, d].
+func jitter(d time.Duration) time.Duration {
+	var b [8]byte
+
+	if d <= 0 {
+		return 0
+	}
+
+	if _, e := rand.Read(b[:]); e != nil {
+		return d
+	}
+
+	return time.Duration(binary.LittleEndian.Uint64(b[:]) % uint64(d+1))
+}
+
+// parseRetryAfter will parse a Retry-After header, which is either a
+// number of seconds or an HTTP date.
+func parseRetryAfter(val string, now time.Time) (time.Duration, bool) {
+	var e error
+	var secs int
+	var t time.Time
+
+	if val = strings.TrimSpace(val); val == "" {
+		return 0, false
+	}
+
+	if secs, e = strconv.Atoi(val); e == nil {
+		if secs < 0 {
+			return 0, false
+		}
+
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, e = http.ParseTime(val); e != nil {
+		return 0, false
+	}
+
+	if t.Before(now) {
+		return 0, true
+	}
+
+	return t.Sub(now), true
+}
+
+// backoff will return the delay before the provided retry (starting
+// at 0), honoring any Retry-After header from the Response.
+func (p *RetryPolicy) backoff(retry int, res *Response) time.Duration {
+	var d time.Duration
+	var exp float64
+	var maxBackoff time.Duration = p.MaxBackoff
+	var minBackoff time.Duration = p.MinBackoff
+	var ok bool
+
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+
+	if minBackoff <= 0 {
+		minBackoff = DefaultMinBackoff
+	}
+
+	if res != nil {
+		d, ok = parseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+		if ok {
+			if d > maxBackoff {
+				d = maxBackoff
+			}
+
+			return d
+		}
+	}
+
+	// Exponential w/ full jitter, using floats to avoid overflow
+	exp = float64(minBackoff) * math.Pow(2, float64(retry))
+	if d = maxBackoff; exp < float64(maxBackoff) {
+		d = time.Duration(exp)
+	}
+
+	return jitter(d)
+}
+
+func (p *RetryPolicy) maxRetries() int {
+	if p.MaxRetries <= 0 {
+		return DefaultMaxRetries
+	}
+
+	return p.MaxRetries
+}
+
+// retry will return true if the attempt should be retried.
+func (p *RetryPolicy) retry(r *Request, res *Response, e error) bool {
+	// Never retry if the caller gave up
+	if r.Context().Err() != nil {
+		return false
+	}
+
+	if !p.RetryNonIdempotent && !isIdempotent(r) {
+		return false
+	}
+
+	if p.ShouldRetry != nil {
+		return p.ShouldRetry(r, res, e)
+	}
+
+	if e != nil {
+		return isRetryableError(e)
+	}
+
+	return p.retryStatus(res.StatusCode)
+}
+
+// retryStatus will return true if the status code is retried.
+func (p *RetryPolicy) retryStatus(statusCode int) bool {
+	var codes []int = p.StatusCodes
+
+	if codes == nil {
+		codes = DefaultRetryStatusCodes
+	}
+
+	for _, code := range codes {
+		if statusCode == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sendRetry will make a single round trip, retrying failed attempts
+// as configured by the Client's RetryPolicy. The Request body is
+// replayed with GetBody, so Requests w/ a body that can't be replayed
+// are only sent once.
+func (c *Client) sendRetry(r *Request) (*Response, error) {
+	var attempt *Request = r
+	var body io.Reader
+	var e error
+	var p *RetryPolicy = c.RetryPolicy
+	var res *Response
+	var timer *time.Timer
+
+	if p == nil {
+		return c.send(r)
+	}
+
+	for retry := 0; ; retry++ {
+		res, e = c.send(attempt)
+
+		if attempt != r {
+			attempt.closeBody()
+		}
+
+		if retry >= p.maxRetries() || !p.retry(r, res, e) {
+			return res, e
+		}
+
+		// Body must be replayable
+		body = nil
+		if r.Body != nil {
+			if r.GetBody == nil {
+				return res, e
+			}
+
+			if body, e = r.GetBody(); e != nil {
+				if res != nil {
+					res.Body.Close()
+				}
+
+				return nil, errors.Newf("failed to get body: %w", e)
+			}
+		}
+
+		timer = time.NewTimer(p.backoff(retry, res))
+
+		// Drain a bit of the body to allow for connection reuse
+		if res != nil {
+			io.CopyN(io.Discard, res.Body, 2<<10)
+			res.Body.Close()
+		}
+
+		select {
+		case <-r.Context().Done():
+			timer.Stop()
+			e = errors.Newf("request aborted: %w", r.Context().Err())
+			return nil, e
+		case <-timer.C:
+		}
+
+		attempt = new(Request)
+		*attempt = *r
+		attempt.Body = body
+	}
+}
@@ -0,0 +1,289 @@
+package core
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/mjwhitta/win/errors"
+)
+
+func TestBackoff(t *testing.T) {
+	var d time.Duration
+	var p *RetryPolicy = &RetryPolicy{
+		MaxBackoff: 50 * time.Millisecond,
+		MinBackoff: 10 * time.Millisecond,
+	}
+	var res *Response
+
+	for retry, limit := range []time.Duration{
+		10 * time.Millisecond,
+		20 * time.Millisecond,
+		40 * time.Millisecond,
+		50 * time.Millisecond,
+		50 * time.Millisecond,
+	} {
+		for i := 0; i < 100; i++ {
+			if d = p.backoff(retry, nil); (d < 0) || (d > limit) {
+				t.Fatalf("retry %d: got %s, expected <= %s", retry, d, limit)
+			}
+		}
+	}
+
+	// No overflow
+	if d = p.backoff(10000, nil); (d < 0) || (d > p.MaxBackoff) {
+		t.Errorf("got %s, expected <= %s", d, p.MaxBackoff)
+	}
+
+	// Retry-After is used as is, up to MaxBackoff
+	res = &Response{Header: Header{}}
+
+	for val, expected := range map[string]time.Duration{
+		"0":  0,
+		"1":  p.MaxBackoff,
+		"-1": -1,
+	} {
+		res.Header.Set("Retry-After", val)
+
+		if d = p.backoff(0, res); expected < 0 {
+			if d > p.MinBackoff {
+				t.Errorf("%s: got %s, expected jitter", val, d)
+			}
+		} else if d != expected {
+			t.Errorf("%s: got %s, expected %s", val, d, expected)
+		}
+	}
+
+	// Defaults
+	p = &RetryPolicy{}
+
+	if d = p.backoff(100, nil); d > DefaultMaxBackoff {
+		t.Errorf("got %s, expected <= %s", d, DefaultMaxBackoff)
+	}
+
+	if n := p.maxRetries(); n != DefaultMaxRetries {
+		t.Errorf("got %d, expected %d", n, DefaultMaxRetries)
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	for _, test := range []struct {
+		e        error
+		expected bool
+	}{
+		{nil, false},
+		{io.EOF, true},
+		{errors.Newf("failed: %w", io.ErrUnexpectedEOF), true},
+		{syscall.ECONNRESET, true},
+		{syscall.ECONNREFUSED, true},
+		{syscall.Errno(12002), true},
+		{syscall.Errno(12031), true},
+		{syscall.Errno(12007), false},
+		{errors.New("other"), false},
+	} {
+		if isRetryableError(test.e) != test.expected {
+			t.Errorf("%v: expected %v", test.e, test.expected)
+		}
+	}
+}
+
+func TestJitter(t *testing.T) {
+	var d time.Duration
+
+	if (jitter(0) != 0) || (jitter(-time.Second) != 0) {
+		t.Error("expected 0 for non-positive durations")
+	}
+
+	for i := 0; i < 1000; i++ {
+		if d = jitter(time.Millisecond); (d < 0) || (d > time.Millisecond) {
+			t.Fatalf("got %s, expected [0, 1ms]", d)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	var d time.Duration
+	var now time.Time = time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)
+	var ok bool
+
+	for _, test := range []struct {
+		expected time.Duration
+		ok       bool
+		val      string
+	}{
+		{5 * time.Second, true, "5"},
+		{5 * time.Second, true, " 5 "},
+		{0, true, "0"},
+		{0, false, "-1"},
+		{0, false, ""},
+		{0, false, "soon"},
+		{time.Minute, true, "Wed, 21 Oct 2015 07:29:00 GMT"},
+		{0, true, "Wed, 21 Oct 2015 07:27:00 GMT"},
+	} {
+		d, ok = parseRetryAfter(test.val, now)
+
+		if (ok != test.ok) || (d != test.expected) {
+			t.Errorf(
+				"%q: got %s, %v, expected %s, %v",
+				test.val,
+				d,
+				ok,
+				test.expected,
+				test.ok,
+			)
+		}
+	}
+}
+
+// TestSendRetry checks that only idempotent requests are retried and
+// that the body is replayed for each attempt.
+func TestSendRetry(t *testing.T) {
+	var attempts map[string]int = map[string]int{}
+	var b Backend
+	var body []byte
+	var c *Client
+	var e error
+	var id int
+	var mutex sync.Mutex
+	var req *Request
+	var res *Response
+	var srv *httptest.Server
+
+	// Fails the first 2 attempts of each request
+	srv = httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				var body []byte
+				var n int
+
+				body, _ = io.ReadAll(r.Body)
+
+				mutex.Lock()
+				attempts[r.URL.Path]++
+				n = attempts[r.URL.Path]
+				mutex.Unlock()
+
+				if n <= 2 {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+
+				w.Write(body)
+			},
+		),
+	)
+	defer srv.Close()
+
+	if b, e = NewStd("test", ""); e != nil {
+		t.Fatal(e)
+	}
+
+	c = NewClient(b)
+	c.RetryPolicy = &RetryPolicy{
+		MaxBackoff: time.Millisecond,
+		MinBackoff: time.Millisecond,
+	}
+
+	for _, test := range []struct {
+		attempts int
+		body     io.Reader
+		key      bool
+		method   string
+		status   int
+	}{
+		{3, nil, false, MethodGet, 200},
+		{3, bytes.NewReader([]byte("body")), false, MethodPut, 200},
+		{1, bytes.NewReader([]byte("body")), false, MethodPost, 503},
+		{3, bytes.NewReader([]byte("body")), true, MethodPost, 200},
+
+		// Body can't be replayed
+		{
+			1,
+			io.MultiReader(bytes.NewReader([]byte("x"))),
+			false,
+			MethodPut,
+			503,
+		},
+	} {
+		id++
+
+		req = NewRequestWithBody(
+			test.method,
+			srv.URL+"/"+strconv.Itoa(id),
+			test.body,
+		)
+
+		if test.key {
+			req.Headers.Set("Idempotency-Key", "abc")
+		}
+
+		if res, e = c.Do(req); e != nil {
+			t.Fatal(e)
+		}
+
+		body, _ = io.ReadAll(res.Body)
+		res.Body.Close()
+
+		if res.StatusCode != test.status {
+			t.Errorf(
+				"%d: got status %d, expected %d",
+				id,
+				res.StatusCode,
+				test.status,
+			)
+		}
+
+		if (res.StatusCode == http.StatusOK) && (test.body != nil) {
+			if string(body) != "body" {
+				t.Errorf("%d: got body %q, expected replay", id, body)
+			}
+		}
+
+		mutex.Lock()
+		if attempts["/"+strconv.Itoa(id)] != test.attempts {
+			t.Errorf(
+				"%d: got %d attempts, expected %d",
+				id,
+				attempts["/"+strconv.Itoa(id)],
+				test.attempts,
+			)
+		}
+		mutex.Unlock()
+	}
+
+	// MaxRetries and RetryNonIdempotent
+	c.RetryPolicy.MaxRetries = 1
+	c.RetryPolicy.RetryNonIdempotent = true
+
+	if res, e = c.Post(srv.URL+"/max", "", []byte("body")); e != nil {
+		t.Fatal(e)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("got status %d, expected 503", res.StatusCode)
+	} else if attempts["/max"] != 2 {
+		t.Errorf("got %d attempts, expected 2", attempts["/max"])
+	}
+
+	// ShouldRetry replaces the classification
+	c.RetryPolicy.ShouldRetry = func(*Request, *Response, error) bool {
+		return false
+	}
+
+	if res, e = c.Get(srv.URL + "/never"); e != nil {
+		t.Fatal(e)
+	}
+	res.Body.Close()
+
+	if attempts["/never"] != 1 {
+		t.Errorf("got %d attempts, expected 1", attempts["/never"])
+	}
+}
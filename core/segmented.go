@@ -0,0 +1,309 @@
+package core
+
+import (
+	"context"
+	goerrors "errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mjwhitta/win/errors"
+)
+
+// DefaultSegments is the number of byte ranges fetched at the same
+// time by Client.DownloadSegmented by default.
+const DefaultSegments int = 4
+
+// minSegmentSize is the smallest byte range worth its own request.
+const minSegmentSize int64 = 1 << 20
+
+// errRangeIgnored is returned when the server responds to a Range
+// request w/ the full resource, or rejects the range, b/c it either
+// doesn't support ranges or the resource has changed.
+var errRangeIgnored = errors.New("server ignored Range")
+
+// segment is an inclusive byte range of a segmented download. The
+// offset is the next byte to write.
+type segment struct {
+	end    int64
+	offset int64
+}
+
+// DownloadSegmented will save the resource at the provided URL to a
+// file, fetching opts.Segments byte ranges at the same time and
+// writing each at its offset. This is much faster than a single
+// stream on high-latency links. Each segment is retried on its own.
+// The server is probed w/ a HEAD request first, and if it doesn't
+// advertise Accept-Ranges and a Content-Length (or the file is
+// small), this falls back to Client.Download. Unlike
+// Client.Download, a failed segmented download is not resumed by a
+// later call. If opts is nil, the defaults are used.
+func (c *Client) DownloadSegmented(
+	ctx context.Context,
+	url string,
+	path string,
+	opts *DownloadOptions,
+) error {
+	var d *download = &download{
+		c:     c,
+		ctx:   ctx,
+		path:  path,
+		total: -1,
+		url:   url,
+	}
+	var e error
+	var ok bool
+	var segments []*segment
+
+	if opts != nil {
+		d.opts = *opts
+	}
+
+	if ok, e = d.probe(); e != nil {
+		return e
+	} else if !ok {
+		return c.Download(ctx, url, path, opts)
+	}
+
+	if segments = d.split(); len(segments) < 2 {
+		return c.Download(ctx, url, path, opts)
+	}
+
+	if e = d.create(); e != nil {
+		return e
+	}
+	defer d.f.Close()
+
+	if e = d.fetchSegments(segments); e != nil {
+		// Segmented files have holes, so they can't be resumed
+		d.f.Close()
+		os.Remove(d.f.Name())
+
+		if goerrors.Is(e, errRangeIgnored) {
+			return c.Download(ctx, url, path, opts)
+		}
+
+		return e
+	}
+
+	return d.finish()
+}
+
+// create will create the temporary file, pre-allocated to the full
+// size, and discard the state of any previous partial download.
+func (d *download) create() error {
+	var e error
+	var flags int = os.O_CREATE | os.O_RDWR | os.O_TRUNC
+
+	if d.f, e = os.OpenFile(d.path+".part", flags, 0o644); e != nil {
+		return errors.Newf("failed to open %s.part: %w", d.path, e)
+	}
+
+	os.Remove(d.statePath())
+
+	if e = d.f.Truncate(d.total); e != nil {
+		d.f.Close()
+		os.Remove(d.f.Name())
+
+		return errors.Newf("failed to allocate %s: %w", d.f.Name(), e)
+	}
+
+	return nil
+}
+
+// fetchRange will make a single Range request for the remainder of
+// the provided segment, writing the response body at its offset.
+func (d *download) fetchRange(ctx context.Context, s *segment) error {
+	var e error
+	var ok bool
+	var r *Request = NewRequestWithContext(ctx, MethodGet, d.url)
+	var res *Response
+	var start int64
+	var validator string = d.validator()
+
+	r.Headers.Set("Accept-Encoding", "identity")
+	r.Headers.Set(
+		"Range",
+		"bytes="+strconv.FormatInt(s.offset, 10)+"-"+
+			strconv.FormatInt(s.end, 10),
+	)
+
+	if validator != "" {
+		r.Headers.Set("If-Range", validator)
+	}
+
+	if res, e = d.c.Do(r); e != nil {
+		return e
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusRequestedRangeNotSatisfiable:
+		// The resource probably changed since it was probed
+		return errRangeIgnored
+	case http.StatusPartialContent:
+		start, _, _, ok = parseContentRange(
+			res.Header.Get("Content-Range"),
+		)
+		if !ok || (start != s.offset) {
+			return errors.Newf(
+				"unexpected Content-Range %s",
+				res.Header.Get("Content-Range"),
+			)
+		}
+	default:
+		return newStatusError(res)
+	}
+
+	return d.writeSegment(
+		io.LimitReader(res.Body, s.end-s.offset+1),
+		s,
+	)
+}
+
+// fetchSegments will fetch all segments at the same time. The first
+// final error of any segment cancels the others. Retryable errors,
+// such as a 503, are retried by the failing segment alone.
+func (d *download) fetchSegments(segments []*segment) error {
+	var cancel context.CancelFunc
+	var ctx context.Context
+	var e error
+	var errs chan error = make(chan error, len(segments))
+
+	ctx, cancel = context.WithCancel(d.ctx)
+	defer cancel()
+
+	for _, s := range segments {
+		go func(s *segment) {
+			errs <- d.retry(
+				ctx,
+				func() (bool, error) {
+					var e error = d.fetchRange(ctx, s)
+					return e == nil, e
+				},
+			)
+		}(s)
+	}
+
+	for range segments {
+		if err := <-errs; (err != nil) && (e == nil) {
+			e = err
+			cancel()
+		}
+	}
+
+	return e
+}
+
+// probe will return whether the server supports byte ranges of the
+// resource. It also records its size and validators.
+func (d *download) probe() (bool, error) {
+	var e error
+	var ranges bool
+	var r *Request = NewRequestWithContext(d.ctx, MethodHead, d.url)
+	var res *Response
+
+	r.Headers.Set("Accept-Encoding", "identity")
+
+	if res, e = d.c.Do(r); e != nil {
+		return false, e
+	}
+	res.Body.Close()
+
+	// Let Client.Download handle any errors
+	if res.StatusCode != http.StatusOK {
+		return false, nil
+	}
+
+	for _, unit := range strings.Split(res.Header.Get("Accept-Ranges"), ",") {
+		if strings.EqualFold(strings.TrimSpace(unit), "bytes") {
+			ranges = true
+		}
+	}
+
+	if !ranges || (res.ContentLength <= 0) {
+		return false, nil
+	}
+
+	d.total = res.ContentLength
+	d.state = downloadState{
+		ETag:         res.Header.Get("ETag"),
+		LastModified: res.Header.Get("Last-Modified"),
+		URL:          d.url,
+	}
+
+	return true, nil
+}
+
+// split will divide the resource into segments of roughly equal size,
+// each at least minSegmentSize bytes.
+func (d *download) split() []*segment {
+	var n int = d.opts.Segments
+	var segments []*segment
+	var size int64
+
+	if n <= 0 {
+		n = DefaultSegments
+	}
+
+	if int64(n) > d.total/minSegmentSize {
+		n = int(d.total / minSegmentSize)
+	}
+
+	if n < 1 {
+		return nil
+	}
+
+	size = d.total / int64(n)
+
+	for i := 0; i < n; i++ {
+		segments = append(
+			segments,
+			&segment{
+				end:    int64(i+1)*size - 1,
+				offset: int64(i) * size,
+			},
+		)
+	}
+
+	// Last segment gets the remainder
+	segments[n-1].end = d.total - 1
+
+	return segments
+}
+
+// writeSegment will write the provided body at the segment's offset.
+// Write errors are wrapped in a writeError, as they can't be resumed.
+func (d *download) writeSegment(body io.Reader, s *segment) error {
+	var b []byte = make([]byte, 32<<10)
+	var e error
+	var n int
+
+	for {
+		n, e = body.Read(b)
+
+		if n > 0 {
+			if _, err := d.f.WriteAt(b[:n], s.offset); err != nil {
+				return &writeError{
+					errors.Newf("failed to write %s: %w", d.f.Name(), err),
+				}
+			}
+
+			s.offset += int64(n)
+		}
+
+		if goerrors.Is(e, io.EOF) {
+			break
+		} else if e != nil {
+			return errors.Newf("failed to read body: %w", e)
+		}
+	}
+
+	if s.offset <= s.end {
+		return errors.Newf("failed to read body: %w", io.ErrUnexpectedEOF)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDownloadSegmented(t *testing.T) {
+	var c *Client
+	var content []byte = bytes.Repeat([]byte("0123456789"), 300000)
+	var e error
+	var out []byte
+	var path string
+	var url string
+
+	for _, code := range []int{
+		// Retried by the failing segment
+		http.StatusServiceUnavailable,
+		// Falls back to Client.Download
+		http.StatusRequestedRangeNotSatisfiable,
+	} {
+		path = filepath.Join(t.TempDir(), "file")
+		c, url, _ = testDownload(t, content, code, 1)
+		defer c.Close()
+
+		e = c.DownloadSegmented(context.Background(), url, path, nil)
+		if e != nil {
+			t.Errorf("%d: %s", code, e)
+			continue
+		}
+
+		if out, e = os.ReadFile(path); e != nil {
+			t.Error(e)
+		} else if !bytes.Equal(out, content) {
+			t.Errorf("%d: content mismatch", code)
+		}
+	}
+}
+
+// TestDownloadSegmentedRanges checks that segments are fetched w/
+// Range requests, and that a server ignoring them falls back to a
+// single stream.
+func TestDownloadSegmentedRanges(t *testing.T) {
+	var c *Client
+	var content []byte = bytes.Repeat([]byte("x"), int(4*minSegmentSize))
+	var e error
+	var f *Fake = NewFake()
+	var out []byte
+	var path string
+	var ranges atomic.Int64
+
+	f.HandleFunc(
+		"/ranges",
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Range") != "" {
+				ranges.Add(1)
+			}
+
+			http.ServeContent(
+				w,
+				r,
+				"file",
+				time.Unix(0, 0),
+				bytes.NewReader(content),
+			)
+		},
+	)
+	f.HandleFunc(
+		"/ignored",
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Write(content)
+		},
+	)
+
+	c = NewClient(f)
+
+	for _, test := range []struct {
+		ranges int64
+		url    string
+	}{
+		{4, "http://example.com/ranges"},
+		{0, "http://example.com/ignored"},
+	} {
+		path = filepath.Join(t.TempDir(), "file")
+		ranges.Store(0)
+
+		e = c.DownloadSegmented(
+			context.Background(),
+			test.url,
+			path,
+			&DownloadOptions{Segments: 4},
+		)
+		if e != nil {
+			t.Errorf("%s: %s", test.url, e)
+			continue
+		}
+
+		if out, e = os.ReadFile(path); e != nil {
+			t.Error(e)
+		} else if !bytes.Equal(out, content) {
+			t.Errorf("%s: content mismatch", test.url)
+		}
+
+		if n := ranges.Load(); n != test.ranges {
+			t.Errorf(
+				"%s: got %d Range requests, expected %d",
+				test.url,
+				n,
+				test.ranges,
+			)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package core
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/mjwhitta/win/errors"
+)
+
+type std struct {
+	base       *http.Transport
+	mutex      sync.Mutex
+	transports map[stdConfig]*http.Transport
+	userAgent  string
+}
+
+// stdConfig is the Client options that require a separate
+// net/http.Transport.
+type stdConfig struct {
+	insecure bool
+	limits   poolLimits
+}
+
+// NewStd will return a Backend that uses the Go standard library. It
+// is available on all platforms and is used in place of WinHTTP and
+// WinINet when not on Windows.
+func NewStd(userAgent string, proxyname string) (Backend, error) {
+	var b *std = &std{
+		transports: map[stdConfig]*http.Transport{},
+		userAgent:  userAgent,
+	}
+	var e error
+
+	// Decompression is handled by the Client, same as WinHTTP and
+	// WinINet
+	b.base = &http.Transport{
+		DisableCompression: true,
+		ForceAttemptHTTP2:  true,
+		Proxy:              http.ProxyFromEnvironment,
+		TLSClientConfig:    &tls.Config{},
+	}
+
+	// Use named proxy, if provided
+	if proxyname != "" {
+		if b.base.Proxy, e = parseProxy(proxyname); e != nil {
+			return nil, errors.Newf("failed to create session: %w", e)
+		}
+	}
+
+	return b, nil
+}
+
+func parseProxy(
+	proxyname string,
+) (func(*http.Request) (*url.URL, error), error) {
+	var e error
+	var uri *url.URL
+
+	// WinHTTP and WinINet accept host:port, so default to http
+	if !strings.Contains(proxyname, "://") {
+		proxyname = "http://" + proxyname
+	}
+
+	if uri, e = url.Parse(proxyname); e != nil {
+		return nil, errors.Newf("proxy %s invalid: %w", proxyname, e)
+	}
+
+	return http.ProxyURL(uri), nil
+}
+
+func (b *std) buildRequest(r *Request) (*http.Request, error) {
+	var e error
+	var req *http.Request
+
+	// Create HTTP request, net/http will use chunked
+	// Transfer-Encoding if the length is unknown
+	req, e = http.NewRequestWithContext(
+		r.Context(),
+		r.Method,
+		r.URL,
+		r.Body,
+	)
+	if e != nil {
+		return nil, errors.Newf("failed to open request: %w", e)
+	}
+
+	req.ContentLength = r.outgoingLength()
+	if req.ContentLength == 0 {
+		req.Body = nil
+	}
+
+	if b.userAgent != "" {
+		req.Header.Set("User-Agent", b.userAgent)
+	}
+
+	// Process cookies
+	for _, c := range r.Cookies() {
+		req.AddCookie(&http.Cookie{Name: c.Name, Value: c.Value})
+	}
+
+	// Process headers
+	for k, vs := range r.Headers {
+		req.Header[k] = append([]string(nil), vs...)
+	}
+
+	return req, nil
+}
+
+func (b *std) buildResponse(res *http.Response) (*Response, error) {
+	var out *Response = &Response{
+		Body:          res.Body,
+		ContentLength: res.ContentLength,
+		Header:        Header(res.Header),
+		Proto:         res.Proto,
+		ProtoMajor:    res.ProtoMajor,
+		ProtoMinor:    res.ProtoMinor,
+		Status:        res.Status,
+		StatusCode:    res.StatusCode,
+	}
+
+	// Parse cookies
+	for _, raw := range res.Header.Values("Set-Cookie") {
+		// Skip malformed cookies
+		if c := parseSetCookie(raw); c != nil {
+			out.AddCookie(c)
+		}
+	}
+
+	return out, nil
+}
+
+// Close will close any idle connections.
+func (b *std) Close() error {
+	b.CloseIdleConnections()
+	return nil
+}
+
+// CloseIdleConnections will close any idle connections.
+func (b *std) CloseIdleConnections() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, t := range b.transports {
+		t.CloseIdleConnections()
+	}
+}
+
+// Send will send the Request using the Go standard library.
+func (b *std) Send(c *Client, r *Request) (*Response, error) {
+	var client = &http.Client{
+		// Redirects are followed by the Client
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Timeout:   c.Timeout,
+		Transport: b.transport(c),
+	}
+	var e error
+	var req *http.Request
+	var res *http.Response
+
+	if req, e = b.buildRequest(r); e != nil {
+		return nil, e
+	}
+
+	// Send HTTP request
+	if res, e = client.Do(req); e != nil {
+		return nil, errors.Newf("failed to send request: %w", e)
+	}
+
+	return b.buildResponse(res)
+}
+
+// transport will return the net/http.Transport matching the Client's
+// options, creating it if needed, so connections are pooled per
+// configuration.
+func (b *std) transport(c *Client) *http.Transport {
+	var cfg stdConfig = stdConfig{
+		insecure: c.TLSClientConfig.InsecureSkipVerify,
+		limits:   c.poolLimits(),
+	}
+	var ok bool
+	var t *http.Transport
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if t, ok = b.transports[cfg]; ok {
+		return t
+	}
+
+	t = b.base.Clone()
+	t.DisableKeepAlives = cfg.limits.disabled
+	t.IdleConnTimeout = cfg.limits.idleTimeout
+	t.MaxConnsPerHost = cfg.limits.maxPerHost
+	t.TLSClientConfig.InsecureSkipVerify = cfg.insecure
+
+	// Same as the WinHTTP/WinINet pool, only limit idle connections
+	// overall, unless disabled
+	if cfg.limits.maxIdle < 0 {
+		t.MaxIdleConnsPerHost = -1
+	} else {
+		t.MaxIdleConns = cfg.limits.maxIdle
+		t.MaxIdleConnsPerHost = cfg.limits.maxIdle
+	}
+
+	b.transports[cfg] = t
+
+	return t
+}
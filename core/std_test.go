@@ -0,0 +1,105 @@
+package core
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStdInsecureSkipVerify(t *testing.T) {
+	var b Backend
+	var c *Client = &Client{}
+	var e error
+	var res *Response
+	var srv *httptest.Server
+
+	srv = httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	if b, e = NewStd("test", ""); e != nil {
+		t.Fatal(e)
+	}
+
+	if _, e = b.Send(c, NewRequest(MethodGet, srv.URL)); e == nil {
+		t.Error("expected certificate error")
+	}
+
+	c.TLSClientConfig.InsecureSkipVerify = true
+
+	if res, e = b.Send(c, NewRequest(MethodGet, srv.URL)); e != nil {
+		t.Fatal(e)
+	}
+
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("got status %d, expected 404", res.StatusCode)
+	}
+}
+
+func TestStdProxy(t *testing.T) {
+	var b Backend
+	var body []byte
+	var c *Client = &Client{}
+	var e error
+	var res *Response
+	var srv *httptest.Server
+
+	srv = httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				// Proxies receive the absolute URL
+				w.Write([]byte(r.URL.String() + " " + r.UserAgent()))
+			},
+		),
+	)
+	defer srv.Close()
+
+	// WinHTTP and WinINet take host:port
+	b, e = NewStd("test", strings.TrimPrefix(srv.URL, "http://"))
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	res, e = b.Send(c, NewRequest(MethodGet, "http://example.com/a"))
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	body, _ = io.ReadAll(res.Body)
+
+	if string(body) != "http://example.com/a test" {
+		t.Errorf("got %q, expected request via proxy", body)
+	}
+
+	if _, e = NewStd("test", "http://[::1"); e == nil {
+		t.Error("expected error for invalid proxy")
+	}
+}
+
+func TestStdTimeout(t *testing.T) {
+	var b Backend
+	var c *Client = &Client{Timeout: 50 * time.Millisecond}
+	var done chan struct{} = make(chan struct{})
+	var e error
+	var srv *httptest.Server
+
+	srv = httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				<-done
+			},
+		),
+	)
+	defer srv.Close()
+	defer close(done)
+
+	if b, e = NewStd("test", ""); e != nil {
+		t.Fatal(e)
+	}
+
+	if _, e = b.Send(c, NewRequest(MethodGet, srv.URL)); e == nil {
+		t.Error("expected timeout")
+	}
+}
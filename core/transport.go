@@ -0,0 +1,142 @@
+package core
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mjwhitta/win/errors"
+)
+
+// Transport is an implementation of net/http.RoundTripper that sends
+// requests using a Client. It allows a Client to be used anywhere a
+// net/http.Client is expected:
+//
+//	var c = &http.Client{Transport: &core.Transport{Client: client}}
+type Transport struct {
+	// Client is used to send requests.
+	Client *Client
+}
+
+func fromStdRequest(req *http.Request) (*Request, error) {
+	var r *Request
+
+	if req.URL == nil {
+		return nil, errors.New("request has no url")
+	}
+
+	r = NewRequestWithBody(req.Method, req.URL.String(), nil)
+	r.ctx = req.Context()
+
+	// Stream body, net/http uses 0 for unknown length
+	if (req.Body != nil) && (req.Body != http.NoBody) {
+		r.Body = req.Body
+		r.ContentLength = req.ContentLength
+
+		if r.ContentLength == 0 {
+			r.ContentLength = -1
+		}
+
+		// Allow for retries
+		if req.GetBody != nil {
+			r.GetBody = func() (io.Reader, error) {
+				return req.GetBody()
+			}
+		}
+	}
+
+	if r.Method == "" {
+		r.Method = MethodGet
+	}
+
+	for k, vs := range req.Header {
+		// Cookies are sent separately
+		if http.CanonicalHeaderKey(k) == "Cookie" {
+			continue
+		}
+
+		r.Headers[k] = append([]string(nil), vs...)
+	}
+
+	for _, c := range req.Cookies() {
+		r.AddCookie(&Cookie{Name: c.Name, Value: c.Value})
+	}
+
+	return r, nil
+}
+
+func toStdResponse(req *http.Request, res *Response) *http.Response {
+	var body io.ReadCloser = http.NoBody
+	var hdrs http.Header = http.Header{}
+	var seen = map[string]bool{}
+
+	for k, vs := range res.Header {
+		for _, v := range vs {
+			hdrs.Add(k, v)
+		}
+	}
+
+	// Ensure received cookies are visible via Response.Cookies()
+	for _, v := range hdrs.Values("Set-Cookie") {
+		seen[strings.TrimSpace(strings.SplitN(v, "=", 2)[0])] = true
+	}
+
+	for _, c := range res.Cookies() {
+		if !seen[c.Name] {
+			hdrs.Add("Set-Cookie", c.String())
+		}
+	}
+
+	if res.Body != nil {
+		body = res.Body
+	}
+
+	return &http.Response{
+		Body:          body,
+		ContentLength: res.ContentLength,
+		Header:        hdrs,
+		Proto:         res.Proto,
+		ProtoMajor:    res.ProtoMajor,
+		ProtoMinor:    res.ProtoMinor,
+		Request:       req,
+		Status:        res.Status,
+		StatusCode:    res.StatusCode,
+		Uncompressed:  res.Uncompressed,
+	}
+}
+
+// CloseIdleConnections will close any idle connections of the
+// underlying Client. It is called by net/http.Client's
+// CloseIdleConnections.
+func (t *Transport) CloseIdleConnections() {
+	if t.Client != nil {
+		t.Client.CloseIdleConnections()
+	}
+}
+
+// RoundTrip will convert the net/http.Request into a Request, send it
+// using the underlying Client, and convert the Response back into a
+// net/http.Response. As required of a net/http.RoundTripper, only a
+// single round trip is made, so redirects are left to the
+// net/http.Client. The Client's RetryPolicy still applies.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var e error
+	var r *Request
+	var res *Response
+
+	if t.Client == nil {
+		return nil, errors.New("no client available")
+	}
+
+	if r, e = fromStdRequest(req); e != nil {
+		return nil, e
+	}
+
+	defer r.closeBody()
+
+	if res, e = t.Client.sendRetry(r); e != nil {
+		return nil, e
+	}
+
+	return toStdResponse(req, res), nil
+}
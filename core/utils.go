@@ -0,0 +1,256 @@
+package core
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/mjwhitta/win/errors"
+)
+
+// chunkedWriter will frame each Write using chunked
+// Transfer-Encoding. Close writes the final zero-length chunk.
+type chunkedWriter struct {
+	w io.Writer
+}
+
+// Close will write the terminating chunk.
+func (cw *chunkedWriter) Close() error {
+	var e error
+
+	_, e = io.WriteString(cw.w, "0\r\n\r\n")
+	return e
+}
+
+// Write will write p as a single chunk.
+func (cw *chunkedWriter) Write(p []byte) (int, error) {
+	var e error
+	var n int
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if _, e = fmt.Fprintf(cw.w, "%x\r\n", len(p)); e != nil {
+		return 0, e
+	}
+
+	if n, e = cw.w.Write(p); e != nil {
+		return n, e
+	}
+
+	if _, e = io.WriteString(cw.w, "\r\n"); e != nil {
+		return n, e
+	}
+
+	return n, nil
+}
+
+// contentLength will return the value of the Content-Length header,
+// or -1 if it is missing or invalid.
+func contentLength(hdrs Header) int64 {
+	var e error
+	var n int64
+	var val string = strings.TrimSpace(hdrs.Get("Content-Length"))
+
+	if val == "" {
+		return -1
+	}
+
+	if n, e = strconv.ParseInt(val, 10, 64); (e != nil) || (n < 0) {
+		return -1
+	}
+
+	return n
+}
+
+// decodeBody will reverse encodeBody.
+func decodeBody(text string, encoding string) ([]byte, error) {
+	var b []byte
+	var e error
+
+	switch encoding {
+	case "":
+		return []byte(text), nil
+	case "base64":
+		if b, e = base64.StdEncoding.DecodeString(text); e != nil {
+			return nil, errors.Newf("failed to decode body: %w", e)
+		}
+
+		return b, nil
+	}
+
+	return nil, errors.Newf("unsupported body encoding %s", encoding)
+}
+
+// encodeBody will return the text and encoding for a recorded body,
+// using base64 for binary data.
+func encodeBody(b []byte) (string, string) {
+	if utf8.Valid(b) {
+		return string(b), ""
+	}
+
+	return base64.StdEncoding.EncodeToString(b), "base64"
+}
+
+func parseHeaders(
+	raw string,
+) (string, int, int, Header, error) {
+	var e error
+	var hdrs = Header{}
+	var major int64
+	var minor int64
+	var proto string
+	var tmp []string
+
+	for _, hdr := range strings.Split(raw, "\r\n") {
+		tmp = strings.SplitN(hdr, ": ", 2)
+
+		if len(tmp) == 2 {
+			hdrs.Add(tmp[0], tmp[1])
+		} else if strings.HasPrefix(hdr, "HTTP") {
+			proto = strings.Fields(hdr)[0]
+			tmp = strings.Split(proto, ".")
+
+			if len(tmp) >= 2 {
+				tmp[0] = strings.Replace(tmp[0], "HTTP/", "", 1)
+
+				major, e = strconv.ParseInt(tmp[0], 10, 64)
+				if e != nil {
+					e = errors.Newf("invalid HTTP version: %w", e)
+					return "", 0, 0, nil, e
+				}
+
+				minor, e = strconv.ParseInt(tmp[1], 10, 64)
+				if e != nil {
+					e = errors.Newf("invalid HTTP version: %w", e)
+					return "", 0, 0, nil, e
+				}
+			}
+		}
+	}
+
+	return proto, int(major), int(minor), hdrs, nil
+}
+
+func parseStatus(code string, text string) (string, int, error) {
+	var e error
+	var n int64
+	var status string = code
+
+	if n, e = strconv.ParseInt(code, 10, 64); e != nil {
+		return "", 0, errors.Newf("status %s invalid: %w", code, e)
+	}
+
+	if text != "" {
+		status += " " + text
+	}
+
+	return status, int(n), nil
+}
+
+// writeBody will stream the Request body to w, using chunked
+// Transfer-Encoding if the length is unknown. It is used by backends
+// that have to write the body themselves.
+func writeBody(w io.Writer, r *Request) error {
+	var cw *chunkedWriter
+	var e error
+	var length int64 = r.outgoingLength()
+
+	switch {
+	case length == 0:
+		return nil
+	case length < 0:
+		cw = &chunkedWriter{w: w}
+
+		if _, e = io.Copy(cw, r.Body); e != nil {
+			return errors.Newf("failed to write data: %w", e)
+		}
+
+		if e = cw.Close(); e != nil {
+			return errors.Newf("failed to write data: %w", e)
+		}
+	default:
+		if _, e = io.CopyN(w, r.Body, length); e != nil {
+			return errors.Newf("failed to write data: %w", e)
+		}
+	}
+
+	return nil
+}
+
+// watchContext will call abort if ctx is done before the returned
+// stop function is called. The stop function reports whether abort
+// was called.
+func watchContext(ctx context.Context, abort func()) func() bool {
+	var aborted = make(chan bool, 1)
+	var done = make(chan struct{})
+	var once sync.Once
+	var result bool
+
+	if ctx.Done() == nil {
+		return func() bool { return false }
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			abort()
+			aborted <- true
+		case <-done:
+			aborted <- false
+		}
+	}()
+
+	return func() bool {
+		once.Do(
+			func() {
+				close(done)
+				result = <-aborted
+			},
+		)
+
+		return result
+	}
+}
+
+// writeFileAtomic will write b to a temp file in the same dir, sync
+// it, then rename it over path, so readers never see a partial file.
+func writeFileAtomic(path string, b []byte) error {
+	var e error
+	var f *os.File
+
+	f, e = os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if e != nil {
+		return errors.Newf("failed to save %s: %w", path, e)
+	}
+
+	if _, e = f.Write(b); e == nil {
+		e = f.Sync()
+	}
+
+	if e != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return errors.Newf("failed to save %s: %w", path, e)
+	}
+
+	if e = f.Close(); e != nil {
+		os.Remove(f.Name())
+		return errors.Newf("failed to save %s: %w", path, e)
+	}
+
+	if e = os.Rename(f.Name(), path); e != nil {
+		os.Remove(f.Name())
+		return errors.Newf("failed to save %s: %w", path, e)
+	}
+
+	return nil
+}
@@ -0,0 +1,601 @@
+package core
+
+import (
+	"context"
+	"encoding/binary"
+	"net/url"
+	"strconv"
+	"sync"
+	"unicode/utf16"
+
+	w32 "github.com/mjwhitta/win/api"
+	"github.com/mjwhitta/win/errors"
+)
+
+type winHTTP struct {
+	hndl  uintptr
+	mutex sync.RWMutex
+	pool  *connPool
+}
+
+// winHTTPWriter is an io.Writer for streaming a request body using
+// WinHttpWriteData.
+type winHTTPWriter uintptr
+
+// Write will write p to the request handle.
+func (w winHTTPWriter) Write(p []byte) (int, error) {
+	var e error
+	var n int64
+
+	if e = w32.WinHTTPWriteData(uintptr(w), p, &n); e != nil {
+		return int(n), e
+	}
+
+	return int(n), nil
+}
+
+// NewWinHTTP will return a Backend that uses WinHTTP.dll.
+func NewWinHTTP(userAgent string, proxyname string) (Backend, error) {
+	var b *winHTTP = &winHTTP{}
+	var e error
+
+	b.pool = newConnPool(b.closeHandle)
+
+	// Create session with automatic proxy or no proxy
+	if proxyname == "" {
+		// No proxy given, use automatic proxy
+		b.hndl, e = w32.WinHTTPOpen(
+			userAgent,
+			w32.Winhttp.WinhttpAccessTypeAutomaticProxy,
+			"",
+			"",
+			0,
+		)
+	} else {
+		// Proxy is provided, use it
+		b.hndl, e = w32.WinHTTPOpen(
+			userAgent,
+			w32.Winhttp.WinhttpAccessTypeNamedProxy,
+			proxyname,
+			"",
+			0,
+		)
+	}
+	if e != nil {
+		return nil, errors.Newf("failed to create session: %w", e)
+	}
+	liveHandles.Add(1)
+
+	return b, nil
+}
+
+func (b *winHTTP) buildRequest(
+	connHndl uintptr,
+	r *Request,
+	uri *url.URL,
+) (uintptr, error) {
+	var e error
+	var flags uintptr
+	var passwd string
+	var query string
+	var reqHndl uintptr
+
+	switch uri.Scheme {
+	case "https":
+		flags = w32.Winhttp.WinhttpFlagSecure
+	}
+
+	// Send query string too
+	if uri.RawQuery != "" {
+		query = "?" + uri.RawQuery
+	}
+
+	// Create HTTP request
+	reqHndl, e = w32.WinHTTPOpenRequest(
+		connHndl,
+		r.Method,
+		uri.Path+query,
+		"",
+		"",
+		[]string{},
+		flags,
+	)
+	if e != nil {
+		return 0, errors.Newf("failed to open request: %w", e)
+	}
+	liveHandles.Add(1)
+
+	// Pass URL credentials, same as WinINet
+	if uri.User != nil {
+		passwd, _ = uri.User.Password()
+
+		e = b.setStringOption(
+			reqHndl,
+			w32.Winhttp.WinhttpOptionUsername,
+			uri.User.Username(),
+		)
+		if e != nil {
+			b.closeHandle(reqHndl)
+			return 0, errors.Newf("failed to set username: %w", e)
+		}
+
+		e = b.setStringOption(
+			reqHndl,
+			w32.Winhttp.WinhttpOptionPassword,
+			passwd,
+		)
+		if e != nil {
+			b.closeHandle(reqHndl)
+			return 0, errors.Newf("failed to set password: %w", e)
+		}
+	}
+
+	return reqHndl, nil
+}
+
+func (b *winHTTP) buildResponse(body *handleBody) (*Response, error) {
+	var code []byte
+	var e error
+	var hdrs Header
+	var major int
+	var minor int
+	var proto string
+	var raw []byte
+	var reqHndl uintptr = body.reqHndl
+	var res *Response
+	var status string
+	var statusCode int
+	var text []byte
+
+	// Get response
+	if e = w32.WinHTTPReceiveResponse(reqHndl); e != nil {
+		return nil, errors.Newf("failed to get response: %w", e)
+	}
+
+	// Get status code
+	code, e = b.queryResponse(
+		reqHndl,
+		w32.Winhttp.WinhttpQueryStatusCode,
+		0,
+	)
+	if e != nil {
+		return nil, e
+	}
+
+	// Get status text
+	text, e = b.queryResponse(
+		reqHndl,
+		w32.Winhttp.WinhttpQueryStatusText,
+		0,
+	)
+	if e != nil {
+		return nil, e
+	}
+
+	status, statusCode, e = parseStatus(string(code), string(text))
+	if e != nil {
+		return nil, e
+	}
+
+	// Get headers
+	raw, e = b.queryResponse(
+		reqHndl,
+		w32.Winhttp.WinhttpQueryRawHeadersCRLF,
+		0,
+	)
+	if e != nil {
+		return nil, e
+	}
+
+	// Parse headers and proto
+	proto, major, minor, hdrs, e = parseHeaders(string(raw))
+	if e != nil {
+		return nil, e
+	}
+
+	res = &Response{
+		Body:          body,
+		ContentLength: contentLength(hdrs),
+		Header:        hdrs,
+		Proto:         proto,
+		ProtoMajor:    major,
+		ProtoMinor:    minor,
+		Status:        status,
+		StatusCode:    statusCode,
+	}
+
+	// Parse cookies
+	for _, c := range b.getCookies(reqHndl) {
+		res.AddCookie(c)
+	}
+
+	return res, nil
+}
+
+// Close will release the session handle. It is safe to call more
+// than once.
+func (b *winHTTP) Close() error {
+	var e error
+
+	b.pool.shutdown()
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if e = b.closeHandle(b.hndl); e != nil {
+		return errors.Newf("failed to close session: %w", e)
+	}
+
+	b.hndl = 0
+
+	return nil
+}
+
+func (b *winHTTP) closeHandle(hndl uintptr) error {
+	var e error
+
+	if hndl == 0 {
+		return nil
+	}
+
+	if e = w32.WinHTTPCloseHandle(hndl); e != nil {
+		return e
+	}
+	liveHandles.Add(-1)
+
+	return nil
+}
+
+// CloseIdleConnections will close any pooled connection handles
+// that are not in use.
+func (b *winHTTP) CloseIdleConnections() {
+	b.pool.closeIdle()
+}
+
+// connect will return a connection handle for the URL's scheme, host,
+// and port, reusing an idle one from the pool if possible.
+func (b *winHTTP) connect(
+	ctx context.Context,
+	uri *url.URL,
+	limits poolLimits,
+) (string, uintptr, error) {
+	var connHndl uintptr
+	var e error
+	var key string
+	var port int64
+
+	if uri.Port() != "" {
+		if port, e = strconv.ParseInt(uri.Port(), 10, 64); e != nil {
+			e = errors.Newf("port %s invalid: %w", uri.Port(), e)
+			return "", 0, e
+		}
+	}
+
+	key = uri.Scheme + "://" + uri.Hostname() + ":" + strconv.Itoa(int(port))
+
+	connHndl, e = b.pool.get(
+		ctx,
+		key,
+		limits,
+		func() (uintptr, error) {
+			var connHndl uintptr
+			var e error
+
+			// The session can be closed concurrently
+			b.mutex.RLock()
+			defer b.mutex.RUnlock()
+
+			if b.hndl == 0 {
+				return 0, errors.New("session closed")
+			}
+
+			connHndl, e = w32.WinHTTPConnect(
+				b.hndl,
+				uri.Hostname(),
+				int(port),
+			)
+			if e != nil {
+				e = errors.Newf("failed to create connection: %w", e)
+				return 0, e
+			}
+			liveHandles.Add(1)
+
+			return connHndl, nil
+		},
+	)
+	if e != nil {
+		return "", 0, e
+	}
+
+	return key, connHndl, nil
+}
+
+func (b *winHTTP) getCookies(reqHndl uintptr) []*Cookie {
+	var buf []byte
+	var cookies []*Cookie
+	var e error
+
+	// Get cookies
+	for i := 0; ; i++ {
+		buf, e = b.queryResponse(
+			reqHndl,
+			w32.Winhttp.WinhttpQuerySetCookie,
+			i,
+		)
+		if e != nil {
+			break
+		}
+
+		// Skip malformed cookies
+		if c := parseSetCookie(string(buf)); c != nil {
+			cookies = append(cookies, c)
+		}
+	}
+
+	return cookies
+}
+
+func (b *winHTTP) queryResponse(
+	reqHndl uintptr,
+	info uintptr,
+	idx int,
+) ([]byte, error) {
+	var buffer []byte
+	var e error
+	var size int
+
+	if idx < 0 {
+		idx = 0
+	}
+
+	e = w32.WinHTTPQueryHeaders(
+		reqHndl,
+		info,
+		"",
+		&buffer,
+		&size,
+		&idx,
+	)
+	if e != nil {
+		buffer = make([]byte, size)
+
+		e = w32.WinHTTPQueryHeaders(
+			reqHndl,
+			info,
+			"",
+			&buffer,
+			&size,
+			&idx,
+		)
+		if e != nil {
+			e = errors.Newf("failed to query info: %w", e)
+			return []byte{}, e
+		}
+	}
+
+	return buffer, nil
+}
+
+// Send will send the Request using WinHTTP.dll. If the Request's
+// context is done before the Response body is closed, the handles
+// are closed to abort any blocking call.
+func (b *winHTTP) Send(c *Client, r *Request) (*Response, error) {
+	var body *handleBody
+	var connHndl uintptr
+	var e error
+	var key string
+	var limits poolLimits = c.poolLimits()
+	var reqHndl uintptr
+	var res *Response
+	var uri *url.URL
+
+	// Parse URL
+	if uri, e = url.Parse(r.URL); e != nil {
+		return nil, errors.Newf("failed to parse url %s: %w", r.URL, e)
+	}
+
+	key, connHndl, e = b.connect(r.Context(), uri, limits)
+	if e != nil {
+		return nil, e
+	}
+
+	if reqHndl, e = b.buildRequest(connHndl, r, uri); e != nil {
+		b.pool.put(key, connHndl, true, limits)
+		return nil, e
+	}
+
+	// Body owns the handles from here on
+	body = &handleBody{
+		avail: w32.WinHTTPQueryDataAvailable,
+		close: b.closeHandle,
+		ctx:   r.Context(),
+		put: func(reuse bool) {
+			b.pool.put(key, connHndl, reuse, limits)
+		},
+		read:    w32.WinHTTPReadData,
+		reqHndl: reqHndl,
+	}
+	body.watch()
+
+	if e = b.setOptions(c, reqHndl); e != nil {
+		body.abort()
+		return nil, body.err(e)
+	}
+
+	if e = b.sendRequest(reqHndl, r); e != nil {
+		body.abort()
+		return nil, body.err(e)
+	}
+
+	if res, e = b.buildResponse(body); e != nil {
+		body.abort()
+		return nil, body.err(e)
+	}
+
+	return res, nil
+}
+
+func (b *winHTTP) sendRequest(reqHndl uintptr, r *Request) error {
+	var e error
+	var length int64
+	var method uintptr
+	var total int
+
+	// Process cookies
+	method = w32.Winhttp.WinhttpAddreqFlagAdd
+	method |= w32.Winhttp.WinhttpAddreqFlagCoalesceWithSemicolon
+
+	for _, c := range r.Cookies() {
+		e = w32.WinHTTPAddRequestHeaders(
+			reqHndl,
+			"Cookie: "+c.Name+"="+c.Value,
+			method,
+		)
+		if e != nil {
+			return errors.Newf("failed to add cookies: %w", e)
+		}
+	}
+
+	// Process headers
+	for _, k := range r.Headers.sortedKeys() {
+		for i, v := range r.Headers[k] {
+			method = w32.Winhttp.WinhttpAddreqFlagAdd
+
+			// Only replace with the first value
+			if i == 0 {
+				method |= w32.Winhttp.WinhttpAddreqFlagReplace
+			}
+
+			e = w32.WinHTTPAddRequestHeaders(reqHndl, k+": "+v, method)
+			if e != nil {
+				e = errors.Newf("failed to add request headers: %w", e)
+				return e
+			}
+		}
+	}
+
+	// Unknown length, so fallback to chunked Transfer-Encoding
+	if length = r.outgoingLength(); length < 0 {
+		method = w32.Winhttp.WinhttpAddreqFlagAdd
+		method |= w32.Winhttp.WinhttpAddreqFlagReplace
+
+		total = int(w32.Winhttp.WinhttpIgnoreRequestTotalLength)
+
+		e = w32.WinHTTPAddRequestHeaders(
+			reqHndl,
+			"Transfer-Encoding: chunked",
+			method,
+		)
+		if e != nil {
+			return errors.Newf("failed to add request headers: %w", e)
+		}
+	} else {
+		total = int(length)
+	}
+
+	// Send HTTP request
+	e = w32.WinHTTPSendRequest(reqHndl, "", 0, nil, 0, total)
+	if e != nil {
+		return errors.Newf("failed to send request: %w", e)
+	}
+
+	// Stream body
+	return writeBody(winHTTPWriter(reqHndl), r)
+}
+
+func (b *winHTTP) setOptions(c *Client, reqHndl uintptr) error {
+	var e error
+	var features uintptr
+	var tlsIgnore uintptr
+	var val []byte
+
+	// Redirects and cookies are handled by the Client
+	features = w32.Winhttp.WinhttpDisableRedirects
+	features |= w32.Winhttp.WinhttpDisableCookies
+
+	// Keep-alive is on by default, same as WinINet
+	if c.DisableKeepAlives {
+		features |= w32.Winhttp.WinhttpDisableKeepAlive
+	}
+
+	val = make([]byte, 4)
+	binary.LittleEndian.PutUint32(val, uint32(features))
+
+	e = w32.WinHTTPSetOption(
+		reqHndl,
+		w32.Winhttp.WinhttpOptionDisableFeature,
+		val,
+		len(val),
+	)
+	if e != nil {
+		return errors.Newf("failed to disable features: %w", e)
+	}
+
+	if c.Timeout > 0 {
+		val = make([]byte, 4)
+		binary.LittleEndian.PutUint32(
+			val,
+			uint32(c.Timeout.Milliseconds()),
+		)
+
+		for opt, name := range map[uintptr]string{
+			w32.Winhttp.WinhttpOptionConnectTimeout:         "connect",
+			w32.Winhttp.WinhttpOptionReceiveResponseTimeout: "response",
+			w32.Winhttp.WinhttpOptionReceiveTimeout:         "receive",
+			w32.Winhttp.WinhttpOptionResolveTimeout:         "resolve",
+			w32.Winhttp.WinhttpOptionSendTimeout:            "send",
+		} {
+			e = w32.WinHTTPSetOption(reqHndl, opt, val, len(val))
+			if e != nil {
+				return errors.Newf(
+					"failed to set %s timeout: %w",
+					name,
+					e,
+				)
+			}
+		}
+	}
+
+	if c.TLSClientConfig.InsecureSkipVerify {
+		tlsIgnore |= w32.Winhttp.SecurityFlagIgnoreUnknownCa
+		tlsIgnore |= w32.Winhttp.SecurityFlagIgnoreCertDateInvalid
+		tlsIgnore |= w32.Winhttp.SecurityFlagIgnoreCertCnInvalid
+		tlsIgnore |= w32.Winhttp.SecurityFlagIgnoreCertWrongUsage
+
+		val = make([]byte, 4)
+		binary.LittleEndian.PutUint32(val, uint32(tlsIgnore))
+
+		e = w32.WinHTTPSetOption(
+			reqHndl,
+			w32.Winhttp.WinhttpOptionSecurityFlags,
+			val,
+			len(val),
+		)
+		if e != nil {
+			return errors.Newf("failed to set security flags: %w", e)
+		}
+	}
+
+	return nil
+}
+
+func (b *winHTTP) setStringOption(
+	reqHndl uintptr,
+	opt uintptr,
+	str string,
+) error {
+	var val []byte
+	var wstr []uint16 = utf16.Encode([]rune(str))
+
+	if len(wstr) == 0 {
+		return nil
+	}
+
+	val = make([]byte, 2*len(wstr))
+	for i, c := range wstr {
+		binary.LittleEndian.PutUint16(val[2*i:], c)
+	}
+
+	// WinHTTP expects the length in characters for strings
+	return w32.WinHTTPSetOption(reqHndl, opt, val, len(wstr))
+}
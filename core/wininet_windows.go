@@ -0,0 +1,562 @@
+package core
+
+import (
+	"context"
+	"encoding/binary"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	w32 "github.com/mjwhitta/win/api"
+	"github.com/mjwhitta/win/errors"
+)
+
+type winINet struct {
+	hndl  uintptr
+	mutex sync.RWMutex
+	pool  *connPool
+}
+
+// winINetWriter is an io.Writer for streaming a request body using
+// InternetWriteFile.
+type winINetWriter uintptr
+
+// Write will write p to the request handle.
+func (w winINetWriter) Write(p []byte) (int, error) {
+	var e error
+	var n int64
+
+	if e = w32.InternetWriteFile(uintptr(w), p, &n); e != nil {
+		return int(n), e
+	}
+
+	return int(n), nil
+}
+
+// NewWinINet will return a Backend that uses WinINet.dll.
+func NewWinINet(userAgent string, proxyname string) (Backend, error) {
+	var b *winINet = &winINet{}
+	var e error
+
+	b.pool = newConnPool(b.closeHandle)
+
+	// Create session with automatic proxy or no proxy
+	if proxyname == "" {
+		// No proxy given, use automatic proxy
+		b.hndl, e = w32.InternetOpenW(
+			userAgent,
+			w32.Wininet.InternetOpenTypePreconfig,
+			"",
+			"",
+			0,
+		)
+	} else {
+		// Proxy is provided, use it
+		b.hndl, e = w32.InternetOpenW(
+			userAgent,
+			w32.Wininet.InternetOpenTypeProxy,
+			proxyname,
+			"",
+			0,
+		)
+	}
+	if e != nil {
+		return nil, errors.Newf("failed to create session: %w", e)
+	}
+	liveHandles.Add(1)
+
+	return b, nil
+}
+
+func (b *winINet) buildRequest(
+	c *Client,
+	connHndl uintptr,
+	r *Request,
+	uri *url.URL,
+) (uintptr, error) {
+	var e error
+	var flags uintptr
+	var query string
+	var reqHndl uintptr
+
+	switch uri.Scheme {
+	case "https":
+		flags = w32.Wininet.InternetFlagSecure
+	}
+
+	// Send query string too
+	if uri.RawQuery != "" {
+		query = "?" + uri.RawQuery
+	}
+
+	// Keep-alive is on by default, same as WinHTTP, and is required
+	// for NTLM auth
+	if !c.DisableKeepAlives {
+		flags |= w32.Wininet.InternetFlagKeepConnection
+	}
+
+	// Redirects are followed by the Client
+	flags |= w32.Wininet.InternetFlagNoAutoRedirect
+
+	// Cookies are handled by the Client's Jar, not the IE cookie store
+	flags |= w32.Wininet.InternetFlagNoCookies
+
+	// Create HTTP request
+	reqHndl, e = w32.HTTPOpenRequestW(
+		connHndl,
+		r.Method,
+		uri.Path+query,
+		"",
+		"",
+		[]string{},
+		flags,
+		0,
+	)
+	if e != nil {
+		return 0, errors.Newf("failed to open request: %w", e)
+	}
+	liveHandles.Add(1)
+
+	return reqHndl, nil
+}
+
+func (b *winINet) buildResponse(body *handleBody) (*Response, error) {
+	var code []byte
+	var e error
+	var hdrs Header
+	var major int
+	var minor int
+	var proto string
+	var raw []byte
+	var reqHndl uintptr = body.reqHndl
+	var res *Response
+	var status string
+	var statusCode int
+	var text []byte
+
+	// Get status code
+	code, e = b.queryResponse(reqHndl, w32.Wininet.HTTPQueryStatusCode, 0)
+	if e != nil {
+		return nil, e
+	}
+
+	// Get status text
+	text, e = b.queryResponse(reqHndl, w32.Wininet.HTTPQueryStatusText, 0)
+	if e != nil {
+		return nil, e
+	}
+
+	status, statusCode, e = parseStatus(string(code), string(text))
+	if e != nil {
+		return nil, e
+	}
+
+	// Get headers
+	raw, e = b.queryResponse(
+		reqHndl,
+		w32.Wininet.HTTPQueryRawHeadersCRLF,
+		0,
+	)
+	if e != nil {
+		return nil, e
+	}
+
+	// Parse headers and proto
+	proto, major, minor, hdrs, e = parseHeaders(string(raw))
+	if e != nil {
+		return nil, e
+	}
+
+	res = &Response{
+		Body:          body,
+		ContentLength: contentLength(hdrs),
+		Header:        hdrs,
+		Proto:         proto,
+		ProtoMajor:    major,
+		ProtoMinor:    minor,
+		Status:        status,
+		StatusCode:    statusCode,
+	}
+
+	// Parse cookies
+	for _, c := range b.getCookies(reqHndl) {
+		res.AddCookie(c)
+	}
+
+	return res, nil
+}
+
+// Close will release the session handle. It is safe to call more
+// than once.
+func (b *winINet) Close() error {
+	var e error
+
+	b.pool.shutdown()
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if e = b.closeHandle(b.hndl); e != nil {
+		return errors.Newf("failed to close session: %w", e)
+	}
+
+	b.hndl = 0
+
+	return nil
+}
+
+func (b *winINet) closeHandle(hndl uintptr) error {
+	var e error
+
+	if hndl == 0 {
+		return nil
+	}
+
+	if e = w32.InternetCloseHandle(hndl); e != nil {
+		return e
+	}
+	liveHandles.Add(-1)
+
+	return nil
+}
+
+// CloseIdleConnections will close any pooled connection handles
+// that are not in use.
+func (b *winINet) CloseIdleConnections() {
+	b.pool.closeIdle()
+}
+
+// connect will return a connection handle for the URL's scheme, host,
+// port, and credentials, reusing an idle one from the pool if
+// possible.
+func (b *winINet) connect(
+	ctx context.Context,
+	uri *url.URL,
+	limits poolLimits,
+) (string, uintptr, error) {
+	var connHndl uintptr
+	var e error
+	var flags uintptr
+	var key string
+	var passwd string
+	var port int64
+
+	passwd, _ = uri.User.Password()
+
+	if uri.Port() != "" {
+		if port, e = strconv.ParseInt(uri.Port(), 10, 64); e != nil {
+			e = errors.Newf("port %s invalid: %w", uri.Port(), e)
+			return "", 0, e
+		}
+	}
+
+	switch uri.Scheme {
+	case "https":
+		flags = w32.Wininet.InternetFlagSecure
+	}
+
+	// WinINet sets credentials on the connection
+	key = uri.Scheme + "://" + uri.Hostname() + ":" + strconv.Itoa(int(port))
+	if uri.User != nil {
+		key = uri.User.String() + "@" + key
+	}
+
+	connHndl, e = b.pool.get(
+		ctx,
+		key,
+		limits,
+		func() (uintptr, error) {
+			var connHndl uintptr
+			var e error
+
+			// The session can be closed concurrently
+			b.mutex.RLock()
+			defer b.mutex.RUnlock()
+
+			if b.hndl == 0 {
+				return 0, errors.New("session closed")
+			}
+
+			connHndl, e = w32.InternetConnectW(
+				b.hndl,
+				uri.Hostname(),
+				int(port),
+				uri.User.Username(),
+				passwd,
+				w32.Wininet.InternetServiceHTTP,
+				flags,
+				0,
+			)
+			if e != nil {
+				e = errors.Newf("failed to create connection: %w", e)
+				return 0, e
+			}
+			liveHandles.Add(1)
+
+			return connHndl, nil
+		},
+	)
+	if e != nil {
+		return "", 0, e
+	}
+
+	return key, connHndl, nil
+}
+
+func (b *winINet) getCookies(reqHndl uintptr) []*Cookie {
+	var buf []byte
+	var cookies []*Cookie
+	var e error
+
+	// Get cookies
+	for i := 0; ; i++ {
+		buf, e = b.queryResponse(
+			reqHndl,
+			w32.Wininet.HTTPQuerySetCookie,
+			i,
+		)
+		if e != nil {
+			break
+		}
+
+		// Skip malformed cookies
+		if c := parseSetCookie(string(buf)); c != nil {
+			cookies = append(cookies, c)
+		}
+	}
+
+	return cookies
+}
+
+func (b *winINet) queryResponse(
+	reqHndl uintptr,
+	info uintptr,
+	idx int,
+) ([]byte, error) {
+	var buffer []byte
+	var e error
+	var size int
+
+	if idx < 0 {
+		idx = 0
+	}
+
+	e = w32.HTTPQueryInfoW(reqHndl, info, &buffer, &size, &idx)
+	if e != nil {
+		buffer = make([]byte, size)
+
+		e = w32.HTTPQueryInfoW(
+			reqHndl,
+			info,
+			&buffer,
+			&size,
+			&idx,
+		)
+		if e != nil {
+			e = errors.Newf("failed to query info: %w", e)
+			return []byte{}, e
+		}
+	}
+
+	return buffer, nil
+}
+
+// Send will send the Request using WinINet.dll. If the Request's
+// context is done before the Response body is closed, the handles
+// are closed to abort any blocking call.
+func (b *winINet) Send(c *Client, r *Request) (*Response, error) {
+	var body *handleBody
+	var connHndl uintptr
+	var e error
+	var key string
+	var limits poolLimits = c.poolLimits()
+	var reqHndl uintptr
+	var res *Response
+	var uri *url.URL
+
+	// Parse URL
+	if uri, e = url.Parse(r.URL); e != nil {
+		return nil, errors.Newf("failed to parse url %s: %w", r.URL, e)
+	}
+
+	key, connHndl, e = b.connect(r.Context(), uri, limits)
+	if e != nil {
+		return nil, e
+	}
+
+	if reqHndl, e = b.buildRequest(c, connHndl, r, uri); e != nil {
+		b.pool.put(key, connHndl, true, limits)
+		return nil, e
+	}
+
+	// Body owns the handles from here on
+	body = &handleBody{
+		avail: w32.InternetQueryDataAvailable,
+		close: b.closeHandle,
+		ctx:   r.Context(),
+		put: func(reuse bool) {
+			b.pool.put(key, connHndl, reuse, limits)
+		},
+		read:    w32.InternetReadFile,
+		reqHndl: reqHndl,
+	}
+	body.watch()
+
+	if e = b.setOptions(c, reqHndl); e != nil {
+		body.abort()
+		return nil, body.err(e)
+	}
+
+	if e = b.sendRequest(reqHndl, r); e != nil {
+		body.abort()
+		return nil, body.err(e)
+	}
+
+	if res, e = b.buildResponse(body); e != nil {
+		body.abort()
+		return nil, body.err(e)
+	}
+
+	return res, nil
+}
+
+func (b *winINet) sendRequest(reqHndl uintptr, r *Request) error {
+	var cookies []string
+	var e error
+	var length int64
+	var method uintptr
+	var total int
+
+	// Process cookies, as a single header since WinINet won't
+	// coalesce them
+	for _, c := range r.Cookies() {
+		cookies = append(cookies, c.Name+"="+c.Value)
+	}
+
+	if len(cookies) > 0 {
+		method = w32.Wininet.HTTPAddreqFlagAdd
+		method |= w32.Wininet.HTTPAddreqFlagReplace
+
+		e = w32.HTTPAddRequestHeadersW(
+			reqHndl,
+			"Cookie: "+strings.Join(cookies, "; "),
+			method,
+		)
+		if e != nil {
+			return errors.Newf("failed to add cookies: %w", e)
+		}
+	}
+
+	// Process headers
+	for _, k := range r.Headers.sortedKeys() {
+		for i, v := range r.Headers[k] {
+			method = w32.Wininet.HTTPAddreqFlagAdd
+
+			// Only replace with the first value
+			if i == 0 {
+				method |= w32.Wininet.HTTPAddreqFlagReplace
+			}
+
+			e = w32.HTTPAddRequestHeadersW(reqHndl, k+": "+v, method)
+			if e != nil {
+				e = errors.Newf("failed to add request headers: %w", e)
+				return e
+			}
+		}
+	}
+
+	// Unknown length, so fallback to chunked Transfer-Encoding
+	if length = r.outgoingLength(); length < 0 {
+		method = w32.Wininet.HTTPAddreqFlagAdd
+		method |= w32.Wininet.HTTPAddreqFlagReplace
+
+		e = w32.HTTPAddRequestHeadersW(
+			reqHndl,
+			"Transfer-Encoding: chunked",
+			method,
+		)
+		if e != nil {
+			return errors.Newf("failed to add request headers: %w", e)
+		}
+	}
+
+	// Send HTTP request
+	if length == 0 {
+		e = w32.HTTPSendRequestW(reqHndl, "", 0, nil, 0)
+		if e != nil {
+			return errors.Newf("failed to send request: %w", e)
+		}
+
+		return nil
+	}
+
+	// Total length is ignored for chunked Transfer-Encoding
+	if length > 0 {
+		total = int(length)
+	}
+
+	e = w32.HTTPSendRequestExW(reqHndl, "", total, 0, 0)
+	if e != nil {
+		return errors.Newf("failed to send request: %w", e)
+	}
+
+	// Stream body
+	if e = writeBody(winINetWriter(reqHndl), r); e != nil {
+		return e
+	}
+
+	if e = w32.HTTPEndRequestW(reqHndl, 0, 0); e != nil {
+		return errors.Newf("failed to send request: %w", e)
+	}
+
+	return nil
+}
+
+func (b *winINet) setOptions(c *Client, reqHndl uintptr) error {
+	var e error
+	var val []byte
+
+	if c.Timeout > 0 {
+		val = make([]byte, 4)
+		binary.LittleEndian.PutUint32(
+			val,
+			uint32(c.Timeout.Milliseconds()),
+		)
+
+		for opt, name := range map[uintptr]string{
+			w32.Wininet.InternetOptionConnectTimeout: "connect",
+			w32.Wininet.InternetOptionReceiveTimeout: "receive",
+			w32.Wininet.InternetOptionSendTimeout:    "send",
+		} {
+			e = w32.InternetSetOptionW(reqHndl, opt, val, len(val))
+			if e != nil {
+				return errors.Newf(
+					"failed to set %s timeout: %w",
+					name,
+					e,
+				)
+			}
+		}
+	}
+
+	if c.TLSClientConfig.InsecureSkipVerify {
+		val = make([]byte, 4)
+		binary.LittleEndian.PutUint32(
+			val,
+			uint32(w32.Wininet.SecuritySetMask),
+		)
+
+		e = w32.InternetSetOptionW(
+			reqHndl,
+			w32.Wininet.InternetOptionSecurityFlags,
+			val,
+			len(val),
+		)
+		if e != nil {
+			return errors.Newf("failed to set security flags: %w", e)
+		}
+	}
+
+	return nil
+}
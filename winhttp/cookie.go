@@ -1,8 +0,0 @@
-package winhttp
-
-// Cookie represents an HTTP cookie sent in the Cookie header of an
-// HTTP Request.
-type Cookie struct {
-	Name  string
-	Value string
-}
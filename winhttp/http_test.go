@@ -0,0 +1,71 @@
+package winhttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+// TestDefaultClient checks that the DefaultClient, w/ a shared Jar,
+// is safe for concurrent use.
+func TestDefaultClient(t *testing.T) {
+	var srv *httptest.Server = httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				var n string = r.URL.Query().Get("n")
+
+				if r.URL.Path == "/redirect" {
+					http.Redirect(w, r, "/?n="+n, http.StatusFound)
+					return
+				}
+
+				http.SetCookie(w, &http.Cookie{Name: "c" + n, Value: n})
+				w.Write([]byte(n))
+			},
+		),
+	)
+	var wg sync.WaitGroup
+
+	defer srv.Close()
+
+	DefaultClient.Jar = NewJar()
+	defer func() {
+		DefaultClient.Jar = nil
+	}()
+
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			var b []byte
+			var e error
+			var n string = strconv.Itoa(i)
+			var res *Response
+
+			defer wg.Done()
+
+			if res, e = Get(srv.URL + "/redirect?n=" + n); e != nil {
+				t.Error(e)
+				return
+			}
+
+			b, e = io.ReadAll(res.Body)
+			res.Body.Close()
+
+			if e != nil {
+				t.Error(e)
+			} else if string(b) != n {
+				t.Errorf("got %q, expected %q", b, n)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if n := len(DefaultClient.Jar.(*Jar).Export()); n != 32 {
+		t.Errorf("got %d cookies, expected 32", n)
+	}
+}
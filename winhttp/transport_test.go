@@ -0,0 +1,89 @@
+package winhttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTransport(t *testing.T) {
+	var b []byte
+	var c *http.Client = &http.Client{Transport: &Transport{}}
+	var e error
+	var req *http.Request
+	var res *http.Response
+	var srv *httptest.Server
+
+	srv = httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				var body []byte
+				var ck *http.Cookie
+				var e error
+				var v string
+
+				if ck, e = r.Cookie("sid"); (e != nil) || (ck.Value != "abc") {
+					t.Errorf("cookie not sent: %v", e)
+				}
+
+				// Multiple values are preserved
+				v = strings.Join(r.Header.Values("X-Test"), ",")
+				if v != "a,b" {
+					t.Errorf("got X-Test %q, expected %q", v, "a,b")
+				}
+
+				body, _ = io.ReadAll(r.Body)
+
+				http.SetCookie(w, &http.Cookie{Name: "new", Value: "1"})
+				w.Header().Set("X-Method", r.Method)
+				w.WriteHeader(http.StatusCreated)
+				w.Write(body)
+			},
+		),
+	)
+	defer srv.Close()
+
+	req, _ = http.NewRequest(
+		http.MethodPost,
+		srv.URL,
+		strings.NewReader("hello"),
+	)
+	req.AddCookie(&http.Cookie{Name: "sid", Value: "abc"})
+	req.Header.Add("X-Test", "a")
+	req.Header.Add("X-Test", "b")
+
+	if res, e = c.Do(req); e != nil {
+		t.Fatal(e)
+	}
+	defer res.Body.Close()
+
+	if b, e = io.ReadAll(res.Body); e != nil {
+		t.Fatal(e)
+	}
+
+	if res.StatusCode != http.StatusCreated {
+		t.Errorf("got status %d, expected 201", res.StatusCode)
+	}
+
+	if res.ProtoMajor != 1 {
+		t.Errorf("got proto %q, expected HTTP/1.x", res.Proto)
+	}
+
+	if res.Header.Get("X-Method") != http.MethodPost {
+		t.Errorf("got X-Method %q", res.Header.Get("X-Method"))
+	}
+
+	if string(b) != "hello" {
+		t.Errorf("got body %q, expected %q", b, "hello")
+	}
+
+	if (len(res.Cookies()) != 1) || (res.Cookies()[0].Name != "new") {
+		t.Errorf("got cookies %v, expected [new=1]", res.Cookies())
+	}
+
+	if res.Request != req {
+		t.Error("Request not set")
+	}
+}
@@ -1,32 +1,144 @@
 package wininet
 
-import "github.com/mjwhitta/win/errors"
+import (
+	"context"
+	"io"
+	neturl "net/url"
 
-// DefaultClient is the default client similar to net/http.
+	"github.com/mjwhitta/win/core"
+)
+
+// Cassette records or replays the traffic of a Client, see
+// core.Cassette.
+type Cassette = core.Cassette
+
+// CassetteMode is the mode of a Cassette.
+type CassetteMode = core.CassetteMode
+
+// Cookie represents an HTTP cookie sent in the Cookie header of an
+// HTTP Request.
+type Cookie = core.Cookie
+
+// DownloadOptions configures Client.Download, see
+// core.DownloadOptions.
+type DownloadOptions = core.DownloadOptions
+
+// Fake is a Backend that dispatches each Request to a registered
+// handler in memory, see core.Fake.
+type Fake = core.Fake
+
+// HARRecorder records the traffic of a Client in HAR 1.2 format, see
+// core.HARRecorder.
+type HARRecorder = core.HARRecorder
+
+// Header represents the key-value pairs in an HTTP header.
+type Header = core.Header
+
+// Jar is an in-memory implementation of net/http.CookieJar, see
+// core.Jar.
+type Jar = core.Jar
+
+// Matcher decides if a Request matches a recorded request, see
+// core.Matcher.
+type Matcher = core.Matcher
+
+// Middleware wraps the send path of a Client, see core.Middleware.
+type Middleware = core.Middleware
+
+// Multipart is a builder for multipart/form-data request bodies, see
+// core.Multipart.
+type Multipart = core.Multipart
+
+// Redirect describes a single hop of a redirect chain.
+type Redirect = core.Redirect
+
+// Request is a struct containing common HTTP request data.
+type Request = core.Request
+
+// RetryPolicy describes when and how a Client retries a failed
+// request, see core.RetryPolicy.
+type RetryPolicy = core.RetryPolicy
+
+// Response is a struct containing common HTTP response data.
+type Response = core.Response
+
+// RoundTripFunc sends a single Request and returns its Response.
+type RoundTripFunc = core.RoundTripFunc
+
+// StatusError is returned by the JSON helpers for non-2xx responses,
+// see core.StatusError.
+type StatusError = core.StatusError
+
+// DefaultClient is the default client similar to net/http. It is
+// safe for concurrent use.
 var DefaultClient *Client
 
+// ErrChecksum is returned by Download when the downloaded file
+// doesn't match the expected SHA-256 digest.
+var ErrChecksum = core.ErrChecksum
+
 // ErrNoCookie is returned by Request's Cookie method when a cookie is
 // not found.
-var ErrNoCookie = errors.New("named cookie not present")
+var ErrNoCookie = core.ErrNoCookie
+
+// ErrUseLastResponse can be returned by Client.CheckRedirect to
+// control how redirects are processed.
+var ErrUseLastResponse = core.ErrUseLastResponse
+
+// Valid CassetteMode values.
+const (
+	CassetteRecord CassetteMode = core.CassetteRecord
+	CassetteReplay CassetteMode = core.CassetteReplay
+)
 
 // Common HTTP methods.
 const (
-	MethodConnect string = "CONNECT"
-	MethodDelete  string = "DELETE"
-	MethodGet     string = "GET"
-	MethodHead    string = "HEAD"
-	MethodOptions string = "OPTIONS"
-	MethodPatch   string = "PATCH"
-	MethodPost    string = "POST"
-	MethodPut     string = "PUT"
-	MethodTrace   string = "TRACE"
+	MethodConnect string = core.MethodConnect
+	MethodDelete  string = core.MethodDelete
+	MethodGet     string = core.MethodGet
+	MethodHead    string = core.MethodHead
+	MethodOptions string = core.MethodOptions
+	MethodPatch   string = core.MethodPatch
+	MethodPost    string = core.MethodPost
+	MethodPut     string = core.MethodPut
+	MethodTrace   string = core.MethodTrace
 )
 
+// Download will save the resource at the provided URL to a file
+// using the DefaultClient, resuming dropped transfers, see
+// core.Client.Download.
+func Download(
+	ctx context.Context,
+	url string,
+	path string,
+	opts *DownloadOptions,
+) error {
+	return DefaultClient.Download(ctx, url, path, opts)
+}
+
+// DownloadSegmented will save the resource at the provided URL to a
+// file using the DefaultClient, fetching several byte ranges at the
+// same time, see core.Client.DownloadSegmented.
+func DownloadSegmented(
+	ctx context.Context,
+	url string,
+	path string,
+	opts *DownloadOptions,
+) error {
+	return DefaultClient.DownloadSegmented(ctx, url, path, opts)
+}
+
 // Get will make a GET request using the DefaultClient.
 func Get(url string) (*Response, error) {
 	return DefaultClient.Get(url)
 }
 
+// GetJSON will make a GET request using the DefaultClient and decode
+// the JSON response body into out.
+func GetJSON(url string, out any) error {
+	return DefaultClient.GetJSON(url, out)
+}
+
 // Head will make a HEAD request using the DefaultClient.
 func Head(url string) (*Response, error) {
 	return DefaultClient.Head(url)
@@ -36,7 +148,95 @@ func init() {
 	DefaultClient, _ = NewClient("Go-http-client/1.1", "")
 }
 
+// NewCassette will return a pointer to a new Cassette instance that
+// uses the provided file, see core.NewCassette.
+func NewCassette(path string, mode CassetteMode) (*Cassette, error) {
+	return core.NewCassette(path, mode)
+}
+
+// NewFake will return a pointer to a new Fake instance with no
+// handlers. It can be assigned to any Client's Backend field.
+func NewFake() *Fake {
+	return core.NewFake()
+}
+
+// NewFileJar will return a pointer to a new Jar instance that is
+// backed by the provided cookies.txt or JSON file, see
+// core.NewFileJar.
+func NewFileJar(path string) (*Jar, error) {
+	return core.NewFileJar(path)
+}
+
+// NewHARRecorder will return a pointer to a new HARRecorder instance
+// with the default redactions.
+func NewHARRecorder() *HARRecorder {
+	return core.NewHARRecorder()
+}
+
+// NewJar will return a pointer to a new, empty Jar instance.
+func NewJar() *Jar {
+	return core.NewJar()
+}
+
+// NewMultipart will return a pointer to a new Multipart instance
+// with a random boundary.
+func NewMultipart() *Multipart {
+	return core.NewMultipart()
+}
+
+// NewMultipartRequest will return a pointer to a new Request instance
+// that streams the Multipart body.
+func NewMultipartRequest(method, url string, m *Multipart) *Request {
+	return core.NewMultipartRequest(method, url, m)
+}
+
+// NewRequest will return a pointer to a new Request instance.
+func NewRequest(method, url string, body ...[]byte) *Request {
+	return core.NewRequest(method, url, body...)
+}
+
+// NewRequestWithBody will return a pointer to a new Request instance
+// that streams the provided io.Reader.
+func NewRequestWithBody(method, url string, body io.Reader) *Request {
+	return core.NewRequestWithBody(method, url, body)
+}
+
+// NewRequestWithContext will return a pointer to a new Request
+// instance that is aborted once the provided context is done.
+func NewRequestWithContext(
+	ctx context.Context,
+	method string,
+	url string,
+	body ...[]byte,
+) *Request {
+	return core.NewRequestWithContext(ctx, method, url, body...)
+}
+
 // Post will make a POST request using the DefaultClient.
 func Post(url, contentType string, body []byte) (*Response, error) {
 	return DefaultClient.Post(url, contentType, body)
 }
+
+// PostForm will make a POST request w/ the provided data URL-encoded
+// as the body using the DefaultClient.
+func PostForm(url string, data neturl.Values) (*Response, error) {
+	return DefaultClient.PostForm(url, data)
+}
+
+// PostJSON will make a POST request w/ in as the JSON body using the
+// DefaultClient and decode the JSON response body into out.
+func PostJSON(url string, in any, out any) error {
+	return DefaultClient.PostJSON(url, in, out)
+}
+
+// PostMultipart will make a POST request w/ the Multipart body using
+// the DefaultClient.
+func PostMultipart(url string, m *Multipart) (*Response, error) {
+	return DefaultClient.PostMultipart(url, m)
+}
+
+// PutJSON will make a PUT request w/ in as the JSON body using the
+// DefaultClient and decode the JSON response body into out.
+func PutJSON(url string, in any, out any) error {
+	return DefaultClient.PutJSON(url, in, out)
+}
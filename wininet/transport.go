@@ -0,0 +1,39 @@
+package wininet
+
+import (
+	"net/http"
+
+	"github.com/mjwhitta/win/core"
+)
+
+// Transport is an implementation of net/http.RoundTripper that sends
+// requests using a Client. It allows a Client to be used anywhere a
+// net/http.Client is expected:
+//
+//	var c = &http.Client{Transport: &wininet.Transport{}}
+type Transport struct {
+	// Client is used to send requests. If nil, DefaultClient is used.
+	Client *Client
+}
+
+// CloseIdleConnections will close any idle connections of the
+// underlying Client. It is called by net/http.Client's
+// CloseIdleConnections.
+func (t *Transport) CloseIdleConnections() {
+	t.client().CloseIdleConnections()
+}
+
+func (t *Transport) client() *Client {
+	if t.Client == nil {
+		return DefaultClient
+	}
+
+	return t.Client
+}
+
+// RoundTrip will convert the net/http.Request into a Request, send it
+// using the underlying Client, and convert the Response back into a
+// net/http.Response.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return (&core.Transport{Client: t.client()}).RoundTrip(req)
+}